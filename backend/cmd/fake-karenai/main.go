@@ -0,0 +1,52 @@
+// Command fake-karenai serves fixture data in the shape of the KarenAI /list
+// endpoint so syncs can run offline. Point the API at it with
+// KAREN_AI_BASE_URL=http://localhost:8090.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"stock-api/internal/fakekarenai"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "address to listen on")
+	fixtures := flag.String("fixtures", "fixtures/karenai", "JSON fixture file or directory")
+	pageSize := flag.Int("page-size", 10, "items per page")
+	token := flag.String("token", "", "bearer token to require (empty accepts any)")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	rateLimitEvery := flag.Int("rate-limit-every", 0, "answer every Nth request with 429")
+	retryAfter := flag.Duration("retry-after", time.Second, "Retry-After sent with injected 429s")
+	serverErrorEvery := flag.Int("server-error-every", 0, "answer every Nth request with 500")
+	malformedEvery := flag.Int("malformed-every", 0, "answer every Nth request with a truncated JSON body")
+	flag.Parse()
+
+	items, err := fakekarenai.LoadFixtures(*fixtures)
+	if err != nil {
+		log.Fatal("Failed to load fixtures:", err)
+	}
+
+	server := fakekarenai.NewServer(items, fakekarenai.Options{
+		PageSize:         *pageSize,
+		Token:            *token,
+		Latency:          *latency,
+		RateLimitEvery:   *rateLimitEvery,
+		RetryAfter:       *retryAfter,
+		ServerErrorEvery: *serverErrorEvery,
+		MalformedEvery:   *malformedEvery,
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/", server)
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(server.Stats())
+	})
+
+	log.Printf("Fake KarenAI serving %d items in %d pages on %s", len(items), server.PageCount(), *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
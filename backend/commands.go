@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"stock-api/internal/database"
+	"stock-api/internal/importer"
+	"stock-api/internal/services"
+)
+
+// runCommand runs a one-off CLI command instead of the API server.
+func runCommand(name string, args []string, stockService *services.StockService) error {
+	switch name {
+	case "import":
+		return runImport(args, stockService)
+	default:
+		return fmt.Errorf("unknown command %q, expected import or migrate", name)
+	}
+}
+
+// runImport loads a CSV or JSON Lines file of analyst ratings and prints the
+// import report as JSON:
+//
+//	stock-api import [-format csv|jsonl] [-map field=column,...] [-dry-run] FILE
+func runImport(args []string, stockService *services.StockService) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "file format, csv or jsonl (detected from the extension by default)")
+	mappingSpec := flags.String("map", "", "column mapping as field=column pairs, e.g. ticker=Symbol,time=Date")
+	dryRun := flags.Bool("dry-run", false, "validate the file without writing anything")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: stock-api import [-format csv|jsonl] [-map field=column,...] [-dry-run] FILE")
+	}
+	path := flags.Arg(0)
+
+	if *format == "" {
+		*format = importer.DetectFormat(path, "")
+	}
+
+	mapping, err := importer.ParseMapping(*mappingSpec)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	report, importErr := stockService.ImportAnalyses(context.Background(), file, importer.Options{Format: *format, Mapping: mapping}, *dryRun)
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	}
+
+	return importErr
+}
+
+// runMigrate applies, rolls back or lists schema migrations:
+//
+//	stock-api migrate up [-to VERSION]
+//	stock-api migrate down [-steps N]
+//	stock-api migrate status
+func runMigrate(args []string, db *sql.DB) error {
+	usage := fmt.Errorf("usage: stock-api migrate up [-to VERSION] | down [-steps N] | status")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "up":
+		flags := flag.NewFlagSet("migrate up", flag.ExitOnError)
+		to := flags.Int("to", 0, "apply migrations up to this version (default: all)")
+		flags.Parse(args[1:])
+
+		applied, err := database.MigrateUp(db, *to)
+		fmt.Printf("%d migrations applied\n", applied)
+		return err
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		flags.Parse(args[1:])
+
+		rolledBack, err := database.MigrateDown(db, *steps)
+		fmt.Printf("%d migrations rolled back\n", rolledBack)
+		return err
+	case "status":
+		statuses, err := database.Status(db)
+		if err != nil {
+			return err
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state = "applied"
+				appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			if status.Unknown {
+				state = "unknown (newer than this binary)"
+			}
+			fmt.Fprintf(writer, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return writer.Flush()
+	default:
+		return usage
+	}
+}
@@ -0,0 +1,73 @@
+// Package actions classifies the free-text analyst action of an analysis
+// ("target raised by", "upgraded by", ...) into a fixed set of action types
+// shared by ingestion, filtering, analytics and scoring.
+package actions
+
+import "strings"
+
+const (
+	Initiated     = "initiated"
+	Upgraded      = "upgraded"
+	Downgraded    = "downgraded"
+	TargetRaised  = "raised"
+	TargetLowered = "lowered"
+	Reiterated    = "reiterated"
+	TargetSet     = "target-set"
+	Other         = "other"
+)
+
+// All lists every action type in display order.
+var All = []string{Initiated, Upgraded, Downgraded, TargetRaised, TargetLowered, Reiterated, TargetSet, Other}
+
+var labels = map[string]string{
+	Initiated:     "Initiated",
+	Upgraded:      "Upgraded",
+	Downgraded:    "Downgraded",
+	TargetRaised:  "Target Raised",
+	TargetLowered: "Target Lowered",
+	Reiterated:    "Reiterated",
+	TargetSet:     "Target Set",
+	Other:         "Other",
+}
+
+// keywords are checked in order, so a rating change wins over a target move
+// mentioned in the same action.
+var keywords = []struct {
+	keyword    string
+	actionType string
+}{
+	{"initiated", Initiated},
+	{"upgrade", Upgraded},
+	{"downgrade", Downgraded},
+	{"raised", TargetRaised},
+	{"lowered", TargetLowered},
+	{"reiterated", Reiterated},
+	{"maintained", Reiterated},
+	{"target set", TargetSet},
+}
+
+// Classify returns the action type of a raw action, or Other when it matches
+// none.
+func Classify(action string) string {
+	action = strings.ToLower(action)
+	for _, k := range keywords {
+		if strings.Contains(action, k.keyword) {
+			return k.actionType
+		}
+	}
+	return Other
+}
+
+// Valid reports whether actionType is one of the action types.
+func Valid(actionType string) bool {
+	_, ok := labels[actionType]
+	return ok
+}
+
+// Label returns the display label of an action type.
+func Label(actionType string) string {
+	if label, ok := labels[actionType]; ok {
+		return label
+	}
+	return labels[Other]
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// Background runs work a request starts but does not wait for, such as a
+// sync or a full rescore. Its tasks get a context that ends with the server,
+// and Wait lets shutdown hold off until they have stopped and released their
+// process locks.
+type Background struct {
+	ctx context.Context
+	wg  sync.WaitGroup
+}
+
+func NewBackground(ctx context.Context) *Background {
+	return &Background{ctx: ctx}
+}
+
+// Go runs task on its own goroutine.
+func (b *Background) Go(task func(ctx context.Context)) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		task(b.ctx)
+	}()
+}
+
+// Wait blocks until every task started so far has returned.
+func (b *Background) Wait() {
+	b.wg.Wait()
+}
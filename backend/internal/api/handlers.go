@@ -1,11 +1,22 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"stock-api/internal/actions"
+	"stock-api/internal/exporter"
+	"stock-api/internal/importer"
 	"stock-api/internal/models"
+	"stock-api/internal/scheduler"
+	"stock-api/internal/scoring"
 	"stock-api/internal/services"
 
 	"github.com/gorilla/mux"
@@ -39,6 +50,38 @@ func writeSuccessResponse(w http.ResponseWriter, data interface{}) {
 	writeJSONResponse(w, http.StatusOK, response)
 }
 
+// parseStockFilters reads the StockFilterParams query parameters shared by
+// the stock listing and the exports.
+func parseStockFilters(r *http.Request) (models.StockFilterParams, error) {
+	query := r.URL.Query()
+	filters := models.StockFilterParams{
+		ActionType: query.Get("action_type"),
+		Brokerage:  query.Get("brokerage"),
+		SortBy:     query.Get("sort_by"),
+	}
+
+	if filters.ActionType != "" && filters.ActionType != "all" && !actions.Valid(filters.ActionType) {
+		return filters, fmt.Errorf("unknown action_type %q", filters.ActionType)
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value **float64
+	}{{"min_target", &filters.MinTarget}, {"max_target", &filters.MaxTarget}} {
+		raw := query.Get(bound.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return filters, fmt.Errorf("invalid %s: must be a number", bound.name)
+		}
+		*bound.value = &value
+	}
+
+	return filters, nil
+}
+
 func HealthHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		writeSuccessResponse(w, map[string]string{
@@ -73,10 +116,10 @@ func GetStocksHandler(stockService *services.StockService) http.HandlerFunc {
 		}
 
 		// Parse filter parameters
-		filters := models.StockFilterParams{
-			ActionType: r.URL.Query().Get("action_type"),
-			Brokerage:  r.URL.Query().Get("brokerage"),
-			SortBy:     r.URL.Query().Get("sort_by"),
+		filters, err := parseStockFilters(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
 		}
 
 		paginatedStocks, err := stockService.GetStocksWithMetricsPaginated(page, pageSize, filters)
@@ -89,7 +132,7 @@ func GetStocksHandler(stockService *services.StockService) http.HandlerFunc {
 	}
 }
 
-func SyncAllStocksHandler(stockService *services.StockService) http.HandlerFunc {
+func SyncAllStocksHandler(stockService *services.StockService, background *Background) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Check if process can start
 		canStart, err := stockService.CanStartStockSync()
@@ -103,10 +146,82 @@ func SyncAllStocksHandler(stockService *services.StockService) http.HandlerFunc
 			return
 		}
 
-		go stockService.SyncAllStocks()
+		// Resume from the last checkpoint unless a full sync is explicitly requested
+		resume := r.URL.Query().Get("resume") != "false"
 
-		writeSuccessResponse(w, map[string]string{
+		// Whether the run actually resumes is only known once it holds the
+		// lock; its sync_runs record has resumed and resumed_from
+		background.Go(func(ctx context.Context) {
+			stockService.SyncAllStocks(ctx, resume)
+		})
+
+		writeSuccessResponse(w, map[string]interface{}{
 			"message": "Syncing all stocks in the background from KarenAI API... this may take a while",
+			"resume":  resume,
+		})
+	}
+}
+
+func RescoreRecommendationsHandler(stockService *services.StockService, background *Background) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locked, err := stockService.IsRescoreRunning()
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to check recommendation rescore status: "+err.Error())
+			return
+		}
+
+		if locked {
+			writeErrorResponse(w, http.StatusConflict, "Recommendation rescore is already running")
+			return
+		}
+
+		background.Go(func(ctx context.Context) {
+			if err := stockService.RescoreAllStocks(ctx); err != nil {
+				fmt.Printf("Recommendation rescore failed: %v\n", err)
+			}
+		})
+
+		writeSuccessResponse(w, map[string]string{
+			"message": "Recalculating all recommendation scores in the background",
+		})
+	}
+}
+
+func GetScoringProfileHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeSuccessResponse(w, stockService.GetScoringProfile())
+	}
+}
+
+func ReloadScoringProfileHandler(stockService *services.StockService, background *Background) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		profile, err := stockService.ReloadScoringProfile()
+		if errors.Is(err, scoring.ErrInvalidProfile) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to reload scoring profile: "+err.Error())
+			return
+		}
+
+		// Rescore so stored scores follow the new profile, unless a rescore
+		// is already running
+		message := "Recalculating all recommendation scores in the background"
+		locked, err := stockService.IsRescoreRunning()
+		if err != nil || locked {
+			message = "A recommendation rescore is already running; scores keep their profile version until rescored"
+		} else {
+			background.Go(func(ctx context.Context) {
+				if err := stockService.RescoreAllStocks(ctx); err != nil {
+					fmt.Printf("Recommendation rescore failed: %v\n", err)
+				}
+			})
+		}
+
+		writeSuccessResponse(w, map[string]interface{}{
+			"profile": profile,
+			"message": message,
 		})
 	}
 }
@@ -121,7 +236,9 @@ func GetStockBySymbolHandler(stockService *services.StockService) http.HandlerFu
 			return
 		}
 
-		stock, err := stockService.GetStockWithMetrics(symbol)
+		includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+		stock, err := stockService.GetStockWithMetrics(symbol, includeArchived)
 		if err != nil {
 			writeErrorResponse(w, http.StatusInternalServerError, "Failed to fetch stock: "+err.Error())
 			return
@@ -136,6 +253,76 @@ func GetStockBySymbolHandler(stockService *services.StockService) http.HandlerFu
 	}
 }
 
+// parseTimelineDate reads a date (2006-01-02) or RFC 3339 timestamp. A bare
+// date used as an upper bound includes that whole day.
+func parseTimelineDate(raw string, upper bool) (*time.Time, error) {
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func GetAnalysisTimelineHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		params := models.AnalysisTimelineParams{
+			Brokerage:       query.Get("brokerage"),
+			ActionType:      query.Get("action_type"),
+			IncludeArchived: query.Get("include_archived") == "true",
+		}
+
+		for _, bound := range []struct {
+			name  string
+			upper bool
+			value **time.Time
+		}{{"from", false, &params.From}, {"to", true, &params.To}} {
+			raw := query.Get(bound.name)
+			if raw == "" {
+				continue
+			}
+			value, err := parseTimelineDate(raw, bound.upper)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid "+bound.name+": expected YYYY-MM-DD or an RFC 3339 timestamp")
+				return
+			}
+			*bound.value = value
+		}
+
+		if raw := query.Get("limit"); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid limit: must be a number")
+				return
+			}
+			params.Limit = limit
+		}
+
+		timeline, err := stockService.GetAnalysisTimeline(mux.Vars(r)["symbol"], params, query.Get("cursor"))
+		if errors.Is(err, services.ErrInvalidTimelineQuery) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get analysis timeline: "+err.Error())
+			return
+		}
+
+		if timeline == nil {
+			writeErrorResponse(w, http.StatusNotFound, "Stock not found")
+			return
+		}
+
+		writeSuccessResponse(w, timeline)
+	}
+}
+
 func RefreshStockDataHandler(stockService *services.StockService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -184,7 +371,12 @@ func GetRecommendationsHandler(stockService *services.StockService) http.Handler
 			pageSize = 20
 		}
 
-		paginatedRecommendations, err := stockService.GetRecommendationsPaginated(page, pageSize)
+		strategy := r.URL.Query().Get("strategy")
+		paginatedRecommendations, err := stockService.GetRecommendationsPaginated(page, pageSize, strategy)
+		if errors.Is(err, services.ErrUnknownStrategy) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if err != nil {
 			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get recommendations: "+err.Error())
 			return
@@ -194,6 +386,24 @@ func GetRecommendationsHandler(stockService *services.StockService) http.Handler
 	}
 }
 
+func GetScoringStrategiesHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeSuccessResponse(w, stockService.ListStrategies())
+	}
+}
+
+func GetBrokerageStatsHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := stockService.ListBrokerageStats()
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get brokerage stats: "+err.Error())
+			return
+		}
+
+		writeSuccessResponse(w, stats)
+	}
+}
+
 func SearchStockHandler(stockService *services.StockService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -237,3 +447,334 @@ func GetMarketIntelligenceOverviewHandler(stockService *services.StockService) h
 		writeSuccessResponse(w, overview)
 	}
 }
+
+func GetSyncRunsHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		pageSize, err := strconv.Atoi(r.URL.Query().Get("page_size"))
+		if err != nil || pageSize < 1 {
+			pageSize = 20
+		}
+
+		runs, err := stockService.GetSyncRunsPaginated(page, pageSize, r.URL.Query().Get("status"))
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get sync runs: "+err.Error())
+			return
+		}
+
+		writeSuccessResponse(w, runs)
+	}
+}
+
+func GetSyncRunHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid sync run ID")
+			return
+		}
+
+		run, err := stockService.GetSyncRun(id)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get sync run: "+err.Error())
+			return
+		}
+
+		if run == nil {
+			writeErrorResponse(w, http.StatusNotFound, "Sync run not found")
+			return
+		}
+
+		writeSuccessResponse(w, run)
+	}
+}
+
+func GetCurrentSyncHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeSuccessResponse(w, stockService.GetCurrentSync())
+	}
+}
+
+func CancelSyncHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !stockService.CancelSync() {
+			writeErrorResponse(w, http.StatusConflict, "No stock sync is running on this instance")
+			return
+		}
+
+		writeSuccessResponse(w, map[string]string{
+			"message": "Stock sync cancellation requested",
+		})
+	}
+}
+
+func GetProcessLocksHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locks, err := stockService.GetProcessLocks()
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get process locks: "+err.Error())
+			return
+		}
+
+		writeSuccessResponse(w, locks)
+	}
+}
+
+func ReleaseProcessLockHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		processName := mux.Vars(r)["process"]
+
+		found, err := stockService.ForceReleaseLock(processName)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to release process lock: "+err.Error())
+			return
+		}
+
+		if !found {
+			writeErrorResponse(w, http.StatusNotFound, "Process not found")
+			return
+		}
+
+		writeSuccessResponse(w, map[string]string{
+			"message": "Process lock released",
+			"process": processName,
+		})
+	}
+}
+
+func GetSchedulerStatusHandler(sched *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := sched.Status()
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get scheduler status: "+err.Error())
+			return
+		}
+
+		writeSuccessResponse(w, status)
+	}
+}
+
+func UpdateSchedulerJobHandler(sched *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var update models.SchedulerJobUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+
+		job, err := sched.UpdateJob(mux.Vars(r)["name"], update)
+		if errors.Is(err, scheduler.ErrUnknownJob) {
+			writeErrorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, scheduler.ErrInvalidJobUpdate) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to update scheduler job: "+err.Error())
+			return
+		}
+
+		writeSuccessResponse(w, job)
+	}
+}
+
+func GetRatingScaleHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeSuccessResponse(w, stockService.GetRatingScale())
+	}
+}
+
+func GetRatingMappingsHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mappings, err := stockService.ListRatingMappings(r.URL.Query().Get("brokerage"))
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get rating mappings: "+err.Error())
+			return
+		}
+
+		writeSuccessResponse(w, mappings)
+	}
+}
+
+// SaveRatingMappingHandler creates or changes a mapping and reports how many
+// stored analyses and stocks it affected.
+func SaveRatingMappingHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input models.RatingMappingInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+
+		change, err := stockService.SaveRatingMapping(r.Context(), input)
+		if errors.Is(err, services.ErrInvalidRatingMapping) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to save rating mapping: "+err.Error())
+			return
+		}
+
+		writeSuccessResponse(w, change)
+	}
+}
+
+func DeleteRatingMappingHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid rating mapping ID")
+			return
+		}
+
+		change, err := stockService.DeleteRatingMapping(r.Context(), id)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete rating mapping: "+err.Error())
+			return
+		}
+
+		if change == nil {
+			writeErrorResponse(w, http.StatusNotFound, "Rating mapping not found")
+			return
+		}
+
+		writeSuccessResponse(w, change)
+	}
+}
+
+func GetUnmappedRatingsHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		unmapped, err := stockService.GetUnmappedRatings()
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get unmapped ratings: "+err.Error())
+			return
+		}
+
+		writeSuccessResponse(w, unmapped)
+	}
+}
+
+// maxImportSize caps the size of an uploaded import file.
+const maxImportSize = 100 << 20
+
+// ImportAnalysesHandler accepts a CSV or JSON Lines file either as the raw
+// request body or as the "file" field of a multipart form.
+func ImportAnalysesHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+		var body io.Reader = r.Body
+		filename := ""
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "Missing import file in form field \"file\": "+err.Error())
+				return
+			}
+			defer file.Close()
+			body = file
+			filename = header.Filename
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = importer.DetectFormat(filename, r.Header.Get("Content-Type"))
+		}
+
+		mapping, err := importer.ParseMapping(r.URL.Query().Get("mapping"))
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		dryRun := r.URL.Query().Get("dry_run") == "true"
+
+		report, err := stockService.ImportAnalyses(r.Context(), body, importer.Options{Format: format, Mapping: mapping}, dryRun)
+		if errors.Is(err, importer.ErrInvalidFile) {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			// Rows ingested before the failure stay committed, so the partial
+			// report is still returned
+			writeJSONResponse(w, http.StatusInternalServerError, Response{
+				Success: false,
+				Data:    report,
+				Error:   "Import failed: " + err.Error(),
+			})
+			return
+		}
+
+		writeSuccessResponse(w, report)
+	}
+}
+
+// exportFlushInterval is how many rows are written between flushes, so
+// clients start receiving data long before a large export finishes.
+const exportFlushInterval = 1000
+
+// ExportStocksHandler streams stocks joined with their analyses and
+// recommendation scores in the format named by the route. It accepts the same
+// filters as GET /stocks, plus the scoring strategy of the joined scores.
+func ExportStocksHandler(stockService *services.StockService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := mux.Vars(r)["format"]
+
+		filters, err := parseStockFilters(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", exporter.ContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"stocks.%s\"", format))
+
+		writer, err := exporter.NewRowWriter(format, w)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		controller := http.NewResponseController(w)
+		written := 0
+
+		strategy := r.URL.Query().Get("strategy")
+		err = stockService.ExportStocks(r.Context(), filters, strategy, func(row models.ExportRow) error {
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+
+			written++
+			if written%exportFlushInterval == 0 && format != exporter.FormatParquet {
+				controller.Flush()
+			}
+			return nil
+		})
+		if err != nil && written == 0 {
+			w.Header().Del("Content-Disposition")
+			if errors.Is(err, services.ErrUnknownStrategy) {
+				writeErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to export stocks: "+err.Error())
+			return
+		}
+		if err != nil {
+			// Rows have already been sent with a 200, so the export can only
+			// be cut short
+			fmt.Printf("Export of stocks as %s failed after %d rows: %v\n", format, written, err)
+			return
+		}
+
+		if err := writer.Close(); err != nil {
+			fmt.Printf("Export of stocks as %s failed to finish: %v\n", format, err)
+		}
+	}
+}
@@ -1,22 +1,46 @@
 package api
 
 import (
+	"stock-api/internal/scheduler"
 	"stock-api/internal/services"
 
 	"github.com/gorilla/mux"
 )
 
-func SetupRoutes(router *mux.Router, stockService *services.StockService) {
+// SetupRoutes registers the API. Work that handlers start in the background
+// runs on background, so shutdown can stop it and wait for it.
+func SetupRoutes(router *mux.Router, stockService *services.StockService, sched *scheduler.Scheduler, background *Background) {
 	api := router.PathPrefix("/api/v1").Subrouter()
 
 	api.HandleFunc("/stocks", GetStocksHandler(stockService)).Methods("GET")
-	api.HandleFunc("/stocks/sync", SyncAllStocksHandler(stockService)).Methods("POST")
+	api.HandleFunc("/stocks/sync", SyncAllStocksHandler(stockService, background)).Methods("POST")
 	api.HandleFunc("/stocks/filter-options", GetFilterOptionsHandler(stockService)).Methods("GET")
 	api.HandleFunc("/stocks/recommendations", GetRecommendationsHandler(stockService)).Methods("GET")
+	api.HandleFunc("/exports/stocks.{format:csv|ndjson|parquet}", ExportStocksHandler(stockService)).Methods("GET")
+	api.HandleFunc("/imports", ImportAnalysesHandler(stockService)).Methods("POST")
+	api.HandleFunc("/recommendations/strategies", GetScoringStrategiesHandler(stockService)).Methods("GET")
+	api.HandleFunc("/brokerages", GetBrokerageStatsHandler(stockService)).Methods("GET")
+	api.HandleFunc("/recommendations/rescore", RescoreRecommendationsHandler(stockService, background)).Methods("POST")
 	api.HandleFunc("/analytics/market-intelligence-overview", GetMarketIntelligenceOverviewHandler(stockService)).Methods("GET")
 	api.HandleFunc("/stocks/{symbol}", GetStockBySymbolHandler(stockService)).Methods("GET")
+	api.HandleFunc("/stocks/{symbol}/analyses", GetAnalysisTimelineHandler(stockService)).Methods("GET")
 	api.HandleFunc("/stocks/{symbol}/refresh", RefreshStockDataHandler(stockService)).Methods("POST")
 	api.HandleFunc("/stocks/search/{symbol}", SearchStockHandler(stockService)).Methods("GET")
+	api.HandleFunc("/sync/current", GetCurrentSyncHandler(stockService)).Methods("GET")
+	api.HandleFunc("/sync/cancel", CancelSyncHandler(stockService)).Methods("POST")
+	api.HandleFunc("/sync/runs", GetSyncRunsHandler(stockService)).Methods("GET")
+	api.HandleFunc("/sync/runs/{id:[0-9]+}", GetSyncRunHandler(stockService)).Methods("GET")
+	api.HandleFunc("/admin/locks", GetProcessLocksHandler(stockService)).Methods("GET")
+	api.HandleFunc("/admin/locks/{process}/release", ReleaseProcessLockHandler(stockService)).Methods("POST")
+	api.HandleFunc("/admin/rating-mappings", GetRatingMappingsHandler(stockService)).Methods("GET")
+	api.HandleFunc("/admin/rating-mappings", SaveRatingMappingHandler(stockService)).Methods("POST")
+	api.HandleFunc("/admin/rating-mappings/unmapped", GetUnmappedRatingsHandler(stockService)).Methods("GET")
+	api.HandleFunc("/admin/rating-mappings/{id:[0-9]+}", DeleteRatingMappingHandler(stockService)).Methods("DELETE")
+	api.HandleFunc("/admin/scoring-profile", GetScoringProfileHandler(stockService)).Methods("GET")
+	api.HandleFunc("/admin/scoring-profile/reload", ReloadScoringProfileHandler(stockService, background)).Methods("POST")
+	api.HandleFunc("/ratings/scale", GetRatingScaleHandler(stockService)).Methods("GET")
+	api.HandleFunc("/scheduler", GetSchedulerStatusHandler(sched)).Methods("GET")
+	api.HandleFunc("/scheduler/jobs/{name}", UpdateSchedulerJobHandler(sched)).Methods("PUT")
 
 	api.HandleFunc("/health", HealthHandler()).Methods("GET")
 }
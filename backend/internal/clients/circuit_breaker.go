@@ -0,0 +1,38 @@
+package clients
+
+import "sync"
+
+// circuitBreaker opens after a number of consecutive upstream failures and
+// stays open until reset, so a dead upstream fails a sync run fast instead of
+// retrying every remaining page.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	failures  int
+}
+
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold}
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures < b.threshold
+}
+
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+func (b *circuitBreaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+}
+
+func (b *circuitBreaker) reset() {
+	b.recordSuccess()
+}
@@ -0,0 +1,47 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Error kinds returned by providers. Use errors.Is to check them.
+var (
+	ErrUnauthorized     = errors.New("upstream rejected credentials")
+	ErrRateLimited      = errors.New("upstream rate limit exceeded")
+	ErrTransient        = errors.New("transient upstream failure")
+	ErrDecode           = errors.New("failed to decode upstream response")
+	ErrUnexpectedStatus = errors.New("unexpected upstream status")
+	ErrCircuitOpen      = errors.New("upstream circuit breaker is open")
+)
+
+type APIError struct {
+	Kind       error
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	msg := e.Kind.Error()
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("%s (status %d)", msg, e.StatusCode)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *APIError) retryable() bool {
+	return e.Kind == ErrTransient || e.Kind == ErrRateLimited
+}
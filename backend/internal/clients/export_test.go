@@ -0,0 +1,9 @@
+package clients
+
+import "time"
+
+// SetBackoff shortens retry delays so tests against a fake upstream run fast.
+func (c *KarenAIClient) SetBackoff(base, max time.Duration) {
+	c.baseBackoff = base
+	c.maxBackoff = max
+}
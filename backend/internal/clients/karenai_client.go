@@ -1,16 +1,41 @@
 package clients
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// DefaultKarenAIBaseURL is the production KarenAI API.
+const DefaultKarenAIBaseURL = "https://api.karenai.click/swechallenge"
+
+const (
+	defaultMaxRetries       = 4
+	defaultBaseBackoff      = 500 * time.Millisecond
+	defaultMaxBackoff       = 15 * time.Second
+	defaultMaxRetryAfter    = 2 * time.Minute
+	defaultBreakerThreshold = 5
+)
+
 type KarenAIClient struct {
 	apiToken string
 	baseURL  string
 	client   *http.Client
+
+	maxRetries    int
+	baseBackoff   time.Duration
+	maxBackoff    time.Duration
+	maxRetryAfter time.Duration
+	breaker       *circuitBreaker
+	recorder      *pageRecorder
 }
 
 type StockAnalysis struct {
@@ -30,21 +55,99 @@ type StockListResponse struct {
 	NextPage string          `json:"next_page"`
 }
 
-func NewKarenAIClient(apiToken string) *KarenAIClient {
+var _ AnalystDataProvider = (*KarenAIClient)(nil)
+var _ RunScoped = (*KarenAIClient)(nil)
+
+// NewKarenAIClient creates a client for the KarenAI API at baseURL, or at
+// DefaultKarenAIBaseURL when baseURL is empty.
+func NewKarenAIClient(apiToken, baseURL string) *KarenAIClient {
+	if baseURL == "" {
+		baseURL = DefaultKarenAIBaseURL
+	}
+
 	return &KarenAIClient{
-		apiToken: apiToken,
-		baseURL:  "https://api.karenai.click/swechallenge",
-		client:   &http.Client{Timeout: 30 * time.Second},
+		apiToken:      apiToken,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		client:        &http.Client{Timeout: 30 * time.Second},
+		maxRetries:    defaultMaxRetries,
+		baseBackoff:   defaultBaseBackoff,
+		maxBackoff:    defaultMaxBackoff,
+		maxRetryAfter: defaultMaxRetryAfter,
+		breaker:       newCircuitBreaker(defaultBreakerThreshold),
+	}
+}
+
+// RecordTo makes the client write every page body it receives, along with
+// the page token it was requested with, to a new directory under dir for
+// each run. ReplayProvider serves such a directory back.
+func (c *KarenAIClient) RecordTo(dir string) {
+	c.recorder = newPageRecorder(dir)
+}
+
+// BeginRun resets the circuit breaker so every sync run gets a fresh chance
+// to reach the upstream, and starts a new recording if recording is enabled.
+func (c *KarenAIClient) BeginRun() {
+	c.breaker.reset()
+	if c.recorder != nil {
+		c.recorder.beginRun()
+	}
+}
+
+// GetStocksList fetches a single page, retrying transient failures and rate
+// limits with exponential backoff. Returned errors are *APIError values whose
+// kind can be checked with errors.Is.
+func (c *KarenAIClient) GetStocksList(ctx context.Context, nextPage string) (*StockListResponse, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if !c.breaker.allow() {
+			return nil, &APIError{Kind: ErrCircuitOpen, Err: lastErr}
+		}
+
+		stockList, err := c.fetchPage(ctx, nextPage)
+		if err == nil {
+			c.breaker.recordSuccess()
+			return stockList, nil
+		}
+		lastErr = err
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.retryable() {
+			return nil, err
+		}
+
+		if apiErr.Kind == ErrTransient {
+			c.breaker.recordFailure()
+		}
+
+		if attempt == c.maxRetries {
+			break
+		}
+
+		delay := c.backoff(attempt)
+		if apiErr.Kind == ErrRateLimited && apiErr.RetryAfter > 0 {
+			delay = apiErr.RetryAfter
+			if delay > c.maxRetryAfter {
+				delay = c.maxRetryAfter
+			}
+		}
+
+		fmt.Printf("Warning: KarenAI request failed (attempt %d/%d), retrying in %v: %v\n", attempt+1, c.maxRetries+1, delay, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
+
+	return nil, lastErr
 }
 
-func (c *KarenAIClient) GetStocksList(nextPage string) (*StockListResponse, error) {
-	url := c.baseURL + "/list"
+func (c *KarenAIClient) fetchPage(ctx context.Context, nextPage string) (*StockListResponse, error) {
+	endpoint := c.baseURL + "/list"
 	if nextPage != "" {
-		url += "?next_page=" + nextPage
+		endpoint += "?next_page=" + url.QueryEscape(nextPage)
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -54,28 +157,106 @@ func (c *KarenAIClient) GetStocksList(nextPage string) (*StockListResponse, erro
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch stocks list: %w", err)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &APIError{Kind: ErrTransient, Err: fmt.Errorf("failed to fetch stocks list: %w", err)}
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, &APIError{Kind: ErrUnauthorized, StatusCode: resp.StatusCode}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &APIError{
+			Kind:       ErrRateLimited,
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout:
+		return nil, &APIError{Kind: ErrTransient, StatusCode: resp.StatusCode}
+	default:
+		return nil, &APIError{Kind: ErrUnexpectedStatus, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &APIError{Kind: ErrTransient, StatusCode: resp.StatusCode, Err: fmt.Errorf("failed to read stocks list: %w", err)}
+	}
+
+	if c.recorder != nil {
+		if err := c.recorder.record(nextPage, resp.StatusCode, body); err != nil {
+			fmt.Printf("Warning: failed to record KarenAI response: %v\n", err)
+		}
 	}
 
 	var stockList StockListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&stockList); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.Unmarshal(body, &stockList); err != nil {
+		return nil, &APIError{Kind: ErrDecode, StatusCode: resp.StatusCode, Err: err}
 	}
 
 	return &stockList, nil
 }
 
-func (c *KarenAIClient) GetAllStocks() ([]StockAnalysis, error) {
+// backoff returns an exponential delay for the given attempt with jitter in
+// the upper half of the window.
+func (c *KarenAIClient) backoff(attempt int) time.Duration {
+	delay := c.baseBackoff << uint(attempt)
+	if delay <= 0 || delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter accepts both forms allowed by RFC 7231: delay-seconds and
+// an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
+
+func (c *KarenAIClient) GetAllStocks(ctx context.Context) ([]StockAnalysis, error) {
+	c.BeginRun()
+
 	var allStocks []StockAnalysis
 	nextPage := ""
 
 	for {
-		response, err := c.GetStocksList(nextPage)
+		response, err := c.GetStocksList(ctx, nextPage)
 		if err != nil {
 			return nil, err
 		}
@@ -89,7 +270,9 @@ func (c *KarenAIClient) GetAllStocks() ([]StockAnalysis, error) {
 		nextPage = response.NextPage
 
 		// Add a small delay to avoid rate limiting
-		time.Sleep(100 * time.Millisecond)
+		if err := sleepContext(ctx, 100*time.Millisecond); err != nil {
+			return nil, err
+		}
 	}
 
 	return allStocks, nil
@@ -0,0 +1,100 @@
+package clients_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"stock-api/internal/clients"
+	"stock-api/internal/fakekarenai"
+)
+
+func newFakeKarenAI(t *testing.T, opts fakekarenai.Options) (*clients.KarenAIClient, *fakekarenai.Server, []clients.StockAnalysis) {
+	t.Helper()
+
+	items, err := fakekarenai.LoadFixtures("../../fixtures/karenai")
+	if err != nil {
+		t.Fatalf("load fixtures: %v", err)
+	}
+
+	fake := fakekarenai.NewServer(items, opts)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := clients.NewKarenAIClient(opts.Token, server.URL)
+	client.SetBackoff(time.Millisecond, 10*time.Millisecond)
+	return client, fake, items
+}
+
+func TestGetAllStocksRetriesRateLimitsAndServerErrors(t *testing.T) {
+	client, fake, items := newFakeKarenAI(t, fakekarenai.Options{
+		PageSize:         10,
+		Token:            "test-token",
+		RateLimitEvery:   2,
+		ServerErrorEvery: 3,
+	})
+
+	got, err := client.GetAllStocks(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllStocks: %v", err)
+	}
+
+	if len(got) != len(items) {
+		t.Fatalf("got %d items, want %d", len(got), len(items))
+	}
+	for i := range items {
+		if got[i].Ticker != items[i].Ticker || !got[i].Time.Equal(items[i].Time) {
+			t.Fatalf("item %d = %s at %v, want %s at %v", i, got[i].Ticker, got[i].Time, items[i].Ticker, items[i].Time)
+		}
+	}
+
+	stats := fake.Stats()
+	if stats.RateLimited == 0 || stats.ServerErrors == 0 {
+		t.Fatalf("expected injected faults, got %+v", stats)
+	}
+	if stats.Pages != fake.PageCount() {
+		t.Fatalf("served %d pages, want %d", stats.Pages, fake.PageCount())
+	}
+}
+
+func TestGetAllStocksFailsOnMalformedPage(t *testing.T) {
+	client, fake, _ := newFakeKarenAI(t, fakekarenai.Options{MalformedEvery: 2})
+
+	_, err := client.GetAllStocks(context.Background())
+	if !errors.Is(err, clients.ErrDecode) {
+		t.Fatalf("got error %v, want %v", err, clients.ErrDecode)
+	}
+
+	// Decode failures are not retried
+	if stats := fake.Stats(); stats.Requests != 2 || stats.Malformed != 1 {
+		t.Fatalf("unexpected requests after malformed page: %+v", stats)
+	}
+}
+
+func TestCircuitBreakerOpensAfterRepeatedServerErrors(t *testing.T) {
+	client, fake, _ := newFakeKarenAI(t, fakekarenai.Options{ServerErrorEvery: 1})
+	ctx := context.Background()
+
+	if _, err := client.GetStocksList(ctx, ""); !errors.Is(err, clients.ErrTransient) {
+		t.Fatalf("got error %v, want %v", err, clients.ErrTransient)
+	}
+	requests := fake.Stats().Requests
+
+	if _, err := client.GetStocksList(ctx, ""); !errors.Is(err, clients.ErrCircuitOpen) {
+		t.Fatalf("got error %v, want %v", err, clients.ErrCircuitOpen)
+	}
+	if got := fake.Stats().Requests; got != requests {
+		t.Fatalf("open circuit sent %d requests", got-requests)
+	}
+
+	// A new run closes the circuit again
+	client.BeginRun()
+	if _, err := client.GetStocksList(ctx, ""); !errors.Is(err, clients.ErrTransient) {
+		t.Fatalf("got error %v after BeginRun, want %v", err, clients.ErrTransient)
+	}
+	if got := fake.Stats().Requests; got == requests {
+		t.Fatal("BeginRun did not close the circuit")
+	}
+}
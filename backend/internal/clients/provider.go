@@ -0,0 +1,58 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	ProviderKarenAI = "karenai"
+)
+
+// AnalystDataProvider is a paginated source of analyst rating changes.
+// StockService only depends on this interface so feeds can be swapped
+// without touching the sync logic.
+type AnalystDataProvider interface {
+	// GetStocksList returns a single page. An empty nextPage requests the
+	// first page; an empty NextPage in the response marks the last one.
+	GetStocksList(ctx context.Context, nextPage string) (*StockListResponse, error)
+	// GetAllStocks walks every page and returns the combined items.
+	GetAllStocks(ctx context.Context) ([]StockAnalysis, error)
+}
+
+// RunScoped is implemented by providers that keep per-run state, such as a
+// circuit breaker, which must be reset before a new sync starts.
+type RunScoped interface {
+	BeginRun()
+}
+
+// ProviderOptions carries the settings providers are built from.
+type ProviderOptions struct {
+	APIToken string
+	// BaseURL overrides the provider's default endpoint, e.g. to point at a
+	// local fake server.
+	BaseURL string
+	// RecordDir, when set, makes the KarenAI provider record raw responses.
+	RecordDir string
+	// ReplayDir is the recording served by the replay provider.
+	ReplayDir string
+}
+
+// NewAnalystDataProvider builds the provider registered under name.
+func NewAnalystDataProvider(name string, opts ProviderOptions) (AnalystDataProvider, error) {
+	switch name {
+	case "", ProviderKarenAI:
+		client := NewKarenAIClient(opts.APIToken, opts.BaseURL)
+		if opts.RecordDir != "" {
+			client.RecordTo(opts.RecordDir)
+		}
+		return client, nil
+	case ProviderReplay:
+		if opts.ReplayDir == "" {
+			return nil, fmt.Errorf("the %s provider requires a recording directory", ProviderReplay)
+		}
+		return NewReplayProvider(opts.ReplayDir)
+	default:
+		return nil, fmt.Errorf("unknown analyst data provider: %s", name)
+	}
+}
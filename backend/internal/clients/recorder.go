@@ -0,0 +1,96 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// recordingManifest lists the pages of a recording in fetch order, one JSON
+// entry per line, so an interrupted run still leaves a readable recording.
+const recordingManifest = "pages.jsonl"
+
+// RecordedPage describes one page response written by a recording client.
+type RecordedPage struct {
+	Seq        int       `json:"seq"`
+	PageToken  string    `json:"page_token"`
+	NextPage   string    `json:"next_page"`
+	File       string    `json:"file"`
+	StatusCode int       `json:"status"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// pageRecorder writes raw page bodies byte for byte, so malformed responses
+// are captured as well, into one directory per sync run.
+type pageRecorder struct {
+	root string
+
+	mu     sync.Mutex
+	runDir string
+	seq    int
+}
+
+func newPageRecorder(root string) *pageRecorder {
+	return &pageRecorder{root: root}
+}
+
+// beginRun starts a new recording directory for the next page fetched.
+func (r *pageRecorder) beginRun() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runDir = ""
+	r.seq = 0
+}
+
+func (r *pageRecorder) record(pageToken string, statusCode int, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.runDir == "" {
+		dir := filepath.Join(r.root, time.Now().UTC().Format("20060102T150405.000000000Z"))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create recording directory: %w", err)
+		}
+		r.runDir = dir
+		fmt.Printf("Recording KarenAI responses to %s\n", dir)
+	}
+
+	r.seq++
+	entry := RecordedPage{
+		Seq:        r.seq,
+		PageToken:  pageToken,
+		File:       fmt.Sprintf("page-%05d.json", r.seq),
+		StatusCode: statusCode,
+		RecordedAt: time.Now().UTC(),
+	}
+
+	// The token is informational only; replay decodes the body itself
+	var page StockListResponse
+	if err := json.Unmarshal(body, &page); err == nil {
+		entry.NextPage = page.NextPage
+	}
+
+	if err := os.WriteFile(filepath.Join(r.runDir, entry.File), body, 0o644); err != nil {
+		return fmt.Errorf("failed to write recorded page: %w", err)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := os.OpenFile(filepath.Join(r.runDir, recordingManifest), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open recording manifest: %w", err)
+	}
+	defer manifest.Close()
+
+	if _, err := manifest.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write recording manifest: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package clients
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const ProviderReplay = "replay"
+
+// ReplayProvider serves pages captured by a recording KarenAIClient, so a
+// dataset attached to a bug report can be ingested again deterministically.
+// Bodies go through the same decoding as live responses, which means a
+// recorded malformed page fails the same way it did upstream.
+type ReplayProvider struct {
+	dir   string
+	pages map[string]RecordedPage
+	// start is the token the recorded run began with. It is empty unless the
+	// run resumed from a checkpoint, in which case its first page stands in
+	// for the first page of the recording.
+	start string
+}
+
+var _ AnalystDataProvider = (*ReplayProvider)(nil)
+
+// NewReplayProvider loads the manifest of the recording in dir. When a page
+// token was recorded more than once, the last response wins. A recording
+// without pages is rejected.
+func NewReplayProvider(dir string) (*ReplayProvider, error) {
+	data, err := os.ReadFile(filepath.Join(dir, recordingManifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording manifest: %w", err)
+	}
+
+	pages := make(map[string]RecordedPage)
+	start := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry RecordedPage
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid recording manifest entry on line %d: %w", line, err)
+		}
+		if len(pages) == 0 {
+			start = entry.PageToken
+		}
+		pages[entry.PageToken] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording manifest: %w", err)
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("recording in %s has no pages", dir)
+	}
+
+	return &ReplayProvider{dir: dir, pages: pages, start: start}, nil
+}
+
+// GetStocksList serves the recorded response for nextPage. An empty
+// nextPage serves the first page of the recording, which for a resumed run
+// is the page it resumed from.
+func (p *ReplayProvider) GetStocksList(ctx context.Context, nextPage string) (*StockListResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if nextPage == "" {
+		nextPage = p.start
+	}
+
+	entry, ok := p.pages[nextPage]
+	if !ok {
+		return nil, &APIError{
+			Kind:       ErrUnexpectedStatus,
+			StatusCode: http.StatusNotFound,
+			Err:        fmt.Errorf("no recorded page for next_page %q", nextPage),
+		}
+	}
+
+	body, err := os.ReadFile(filepath.Join(p.dir, entry.File))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recorded page: %w", err)
+	}
+
+	var stockList StockListResponse
+	if err := json.Unmarshal(body, &stockList); err != nil {
+		return nil, &APIError{Kind: ErrDecode, StatusCode: entry.StatusCode, Err: err}
+	}
+
+	return &stockList, nil
+}
+
+func (p *ReplayProvider) GetAllStocks(ctx context.Context) ([]StockAnalysis, error) {
+	var allStocks []StockAnalysis
+	nextPage := ""
+
+	for {
+		response, err := p.GetStocksList(ctx, nextPage)
+		if err != nil {
+			return nil, err
+		}
+
+		allStocks = append(allStocks, response.Items...)
+
+		if response.NextPage == "" {
+			return allStocks, nil
+		}
+		nextPage = response.NextPage
+	}
+}
@@ -0,0 +1,56 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayResumedRecording(t *testing.T) {
+	recorder := newPageRecorder(t.TempDir())
+	recorder.beginRun()
+
+	// A run resumed from checkpoint "MSFT" never requests the first page
+	pages := []struct {
+		token string
+		page  StockListResponse
+	}{
+		{"MSFT", StockListResponse{Items: []StockAnalysis{{Ticker: "NVDA"}}, NextPage: "NVDA"}},
+		{"NVDA", StockListResponse{Items: []StockAnalysis{{Ticker: "TSLA"}}}},
+	}
+	for _, p := range pages {
+		body, err := json.Marshal(p.page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := recorder.record(p.token, 200, body); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	provider, err := NewReplayProvider(recorder.runDir)
+	if err != nil {
+		t.Fatalf("NewReplayProvider: %v", err)
+	}
+
+	items, err := provider.GetAllStocks(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllStocks: %v", err)
+	}
+	if len(items) != 2 || items[0].Ticker != "NVDA" || items[1].Ticker != "TSLA" {
+		t.Fatalf("replayed %+v, want NVDA and TSLA", items)
+	}
+}
+
+func TestReplayRejectsEmptyRecording(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, recordingManifest), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewReplayProvider(dir); err == nil {
+		t.Fatal("expected an error for a recording without pages")
+	}
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"stock-api/internal/actions"
+	"stock-api/internal/pricing"
+	"stock-api/internal/repository"
+)
+
+// backfillBatchSize is how many rows each backfill UPDATE rewrites. The
+// backfills below are the data steps of migrations, see dataSteps.
+const backfillBatchSize = 1000
+
+// backfillPriceTargets parses the targets of analyses stored before numeric
+// targets existed, walking them in id order.
+func backfillPriceTargets(db *sql.DB) (int, error) {
+	updated := 0
+	lastID := 0
+
+	for {
+		rows, err := db.Query(`
+			SELECT id, COALESCE(target_from, ''), COALESCE(target_to, '')
+			FROM stock_analysis
+			WHERE id > $1 AND target_parse_status = $2
+			ORDER BY id
+			LIMIT $3`, lastID, pricing.StatusPending, backfillBatchSize)
+		if err != nil {
+			return updated, fmt.Errorf("failed to read pending price targets: %w", err)
+		}
+
+		placeholders := []string{}
+		args := []any{}
+		for rows.Next() {
+			var id int
+			var from, to string
+			if err := rows.Scan(&id, &from, &to); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			lastID = id
+
+			targets := pricing.ParseTargets(from, to)
+			var currency any
+			if targets.Currency != "" {
+				currency = targets.Currency
+			}
+
+			n := len(args)
+			placeholders = append(placeholders, fmt.Sprintf("($%d::INT, $%d::DECIMAL, $%d::DECIMAL, $%d::VARCHAR, $%d::VARCHAR)", n+1, n+2, n+3, n+4, n+5))
+			args = append(args, id, targets.From, targets.To, currency, targets.Status)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+
+		if len(placeholders) == 0 {
+			return updated, nil
+		}
+
+		query := `
+			UPDATE stock_analysis AS sa SET
+				target_from_value = v.target_from_value,
+				target_to_value = v.target_to_value,
+				target_currency = v.target_currency,
+				target_parse_status = v.target_parse_status
+			FROM (VALUES ` + strings.Join(placeholders, ", ") + `)
+				AS v(id, target_from_value, target_to_value, target_currency, target_parse_status)
+			WHERE sa.id = v.id`
+
+		if _, err := db.Exec(query, args...); err != nil {
+			return updated, fmt.Errorf("failed to backfill price targets: %w", err)
+		}
+		updated += len(placeholders)
+
+		if len(placeholders) < backfillBatchSize {
+			return updated, nil
+		}
+	}
+}
+
+// backfillActionTypes classifies the actions of analyses stored before action
+// types existed, walking them in id order.
+func backfillActionTypes(db *sql.DB) (int, error) {
+	updated := 0
+	lastID := 0
+
+	for {
+		rows, err := db.Query(`
+			SELECT id, COALESCE(action, '')
+			FROM stock_analysis
+			WHERE id > $1 AND action_type IS NULL
+			ORDER BY id
+			LIMIT $2`, lastID, backfillBatchSize)
+		if err != nil {
+			return updated, fmt.Errorf("failed to read unclassified actions: %w", err)
+		}
+
+		placeholders := []string{}
+		args := []any{}
+		for rows.Next() {
+			var id int
+			var action string
+			if err := rows.Scan(&id, &action); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			lastID = id
+
+			n := len(args)
+			placeholders = append(placeholders, fmt.Sprintf("($%d::INT, $%d::VARCHAR)", n+1, n+2))
+			args = append(args, id, actions.Classify(action))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+
+		if len(placeholders) == 0 {
+			return updated, nil
+		}
+
+		query := `
+			UPDATE stock_analysis AS sa SET action_type = v.action_type
+			FROM (VALUES ` + strings.Join(placeholders, ", ") + `) AS v(id, action_type)
+			WHERE sa.id = v.id`
+
+		if _, err := db.Exec(query, args...); err != nil {
+			return updated, fmt.Errorf("failed to backfill action types: %w", err)
+		}
+		updated += len(placeholders)
+
+		if len(placeholders) < backfillBatchSize {
+			return updated, nil
+		}
+	}
+}
+
+// resolveCanonicalRatings maps the raw ratings of analyses stored before the
+// rating taxonomy existed.
+func resolveCanonicalRatings(db *sql.DB) (int, error) {
+	updated, _, err := repository.NewRatingMappingRepository(db).ResolveStoredRatings(true)
+	return updated, err
+}
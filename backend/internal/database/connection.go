@@ -3,7 +3,6 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"os"
 
 	_ "github.com/lib/pq"
 )
@@ -20,18 +19,3 @@ func Connect(databaseURL string) (*sql.DB, error) {
 
 	return db, nil
 }
-
-func Migrate(db *sql.DB) error {
-	// Read the SQL migration file
-	content, err := os.ReadFile("scripts/init-db.sql")
-	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
-	}
-
-	// Execute the SQL content directly
-	if _, err := db.Exec(string(content)); err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
-	}
-
-	return nil
-}
\ No newline at end of file
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned when the database has migrations applied that
+// this binary does not know, i.e. it was migrated by a newer release.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// dataSteps fill columns that need Go logic. A data step runs right after the
+// up SQL of its migration and must be idempotent, like the SQL itself.
+var dataSteps = map[int]func(db *sql.DB) (int, error){
+	4: backfillPriceTargets,
+	5: backfillActionTypes,
+	6: resolveCanonicalRatings,
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change, loaded from the embedded
+// migrations/NNNN_name.up.sql and NNNN_name.down.sql files.
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+	data    func(db *sql.DB) (int, error)
+}
+
+// MigrationStatus is a migration together with whether it is applied.
+// Unknown migrations are applied in the database but missing from this
+// binary.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+// loadMigrations reads the embedded migrations, ordered by version. Versions
+// must start at 1 and have no gaps, and each needs an up and a down file.
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2], data: dataSteps[version]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+		if match[3] == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be consecutive from 1, found %d at position %d", migration.Version, i+1)
+		}
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", migration.Version)
+		}
+	}
+	for version := range dataSteps {
+		if version < 1 || version > len(migrations) {
+			return nil, fmt.Errorf("data step registered for unknown migration %d", version)
+		}
+	}
+
+	return migrations, nil
+}
+
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+type appliedMigration struct {
+	name      string
+	appliedAt time.Time
+}
+
+func appliedMigrations(db *sql.DB) (map[int]appliedMigration, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var migration appliedMigration
+		if err := rows.Scan(&version, &migration.name, &migration.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = migration
+	}
+
+	return applied, rows.Err()
+}
+
+// checkSchemaVersion fails with ErrSchemaTooNew when the database has a
+// migration applied that is newer than the latest one known.
+func checkSchemaVersion(migrations []Migration, applied map[int]appliedMigration) error {
+	latest := len(migrations)
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: migration %d (%s) is applied but this binary only knows up to %d",
+				ErrSchemaTooNew, version, applied[version].name, latest)
+		}
+	}
+	return nil
+}
+
+// Migrate brings the schema up to date on startup. It refuses to run against
+// a database migrated by a newer release.
+func Migrate(db *sql.DB) error {
+	_, err := MigrateUp(db, 0)
+	return err
+}
+
+// MigrateUp applies every pending migration up to and including target, or
+// all of them when target is 0, and returns how many it applied. Migrations
+// are idempotent, so one interrupted halfway is simply run again.
+func MigrateUp(db *sql.DB, target int) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkSchemaVersion(migrations, applied); err != nil {
+		return 0, err
+	}
+	if target < 0 || target > len(migrations) {
+		return 0, fmt.Errorf("unknown migration version %d", target)
+	}
+
+	count := 0
+	for _, migration := range migrations {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, done := applied[migration.Version]; done {
+			continue
+		}
+
+		if _, err := db.Exec(migration.up); err != nil {
+			return count, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		if migration.data != nil {
+			rows, err := migration.data(db)
+			if err != nil {
+				return count, fmt.Errorf("data step of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+			}
+			if rows > 0 {
+				fmt.Printf("Migration %d (%s) updated %d rows\n", migration.Version, migration.Name, rows)
+			}
+		}
+
+		_, err := db.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING`,
+			migration.Version, migration.Name)
+		if err != nil {
+			return count, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		fmt.Printf("Applied migration %04d_%s\n", migration.Version, migration.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown rolls back the steps most recently applied migrations and
+// returns how many it rolled back.
+func MigrateDown(db *sql.DB, steps int) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkSchemaVersion(migrations, applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := migrations[i]
+		if _, done := applied[migration.Version]; !done {
+			continue
+		}
+
+		if _, err := db.Exec(migration.down); err != nil {
+			return count, fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		if _, err := db.Exec(`DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+			return count, fmt.Errorf("failed to unrecord migration %d: %w", migration.Version, err)
+		}
+		fmt.Printf("Rolled back migration %04d_%s\n", migration.Version, migration.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// Status lists every known migration with whether it is applied, followed by
+// any applied migrations this binary does not know.
+func Status(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &record.appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	unknown := []int{}
+	for version := range applied {
+		if version > len(migrations) {
+			unknown = append(unknown, version)
+		}
+	}
+	sort.Ints(unknown)
+	for _, version := range unknown {
+		record := applied[version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Name:      record.name,
+			Applied:   true,
+			AppliedAt: &record.appliedAt,
+			Unknown:   true,
+		})
+	}
+
+	return statuses, nil
+}
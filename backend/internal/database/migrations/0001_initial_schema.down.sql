@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS recommendation_scores;
+DROP TABLE IF EXISTS stock_analysis;
+DROP TABLE IF EXISTS process_control;
+DROP TABLE IF EXISTS stocks;
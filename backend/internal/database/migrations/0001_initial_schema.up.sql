@@ -1,11 +1,6 @@
--- Initialize the stock database
--- This script runs automatically when the database starts
+-- Core tables. Every statement is idempotent, so databases created by the
+-- old init-db.sql script adopt this migration without changes.
 
--- Note: Database should already be selected via connection string
--- CREATE DATABASE IF NOT EXISTS stockdb;
--- \c stockdb;
-
--- Create stocks table
 CREATE TABLE IF NOT EXISTS stocks (
     id SERIAL PRIMARY KEY,
     symbol VARCHAR(10) NOT NULL UNIQUE,
@@ -14,7 +9,6 @@ CREATE TABLE IF NOT EXISTS stocks (
     updated_at TIMESTAMP DEFAULT NOW()
 );
 
--- Create stock_analysis table
 CREATE TABLE IF NOT EXISTS stock_analysis (
     id SERIAL PRIMARY KEY,
     stock_id INT REFERENCES stocks(id),
@@ -28,7 +22,7 @@ CREATE TABLE IF NOT EXISTS stock_analysis (
     created_at TIMESTAMP DEFAULT NOW()
 );
 
--- Create process_control table for managing background processes
+-- Background processes and their locks
 CREATE TABLE IF NOT EXISTS process_control (
     id SERIAL PRIMARY KEY,
     process_name VARCHAR(100) NOT NULL UNIQUE,
@@ -39,7 +33,7 @@ CREATE TABLE IF NOT EXISTS process_control (
     updated_at TIMESTAMP DEFAULT NOW()
 );
 
--- Create recommendation_scores table for pre-calculated scores
+-- Pre-calculated recommendation scores
 CREATE TABLE IF NOT EXISTS recommendation_scores (
     id SERIAL PRIMARY KEY,
     stock_id INT REFERENCES stocks(id) ON DELETE CASCADE,
@@ -58,10 +52,9 @@ CREATE TABLE IF NOT EXISTS recommendation_scores (
     UNIQUE(stock_id)
 );
 
--- Create unique constraint to prevent duplicate analysis for same stock on same date
+-- Prevent duplicate analyses for the same stock, date and brokerage
 CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_analysis_unique ON stock_analysis(stock_id, analysis_date, brokerage);
 
--- Create indexes for better performance
 CREATE INDEX IF NOT EXISTS idx_stocks_symbol ON stocks(symbol);
 CREATE INDEX IF NOT EXISTS idx_stock_analysis_stock_id ON stock_analysis(stock_id);
 CREATE INDEX IF NOT EXISTS idx_stock_analysis_date ON stock_analysis(analysis_date);
@@ -70,16 +63,7 @@ CREATE INDEX IF NOT EXISTS idx_recommendation_scores_total_score ON recommendati
 CREATE INDEX IF NOT EXISTS idx_recommendation_scores_stock_id ON recommendation_scores(stock_id);
 CREATE INDEX IF NOT EXISTS idx_recommendation_scores_confidence ON recommendation_scores(confidence);
 
--- Insert process control entries
-INSERT INTO process_control (process_name, interval_minutes) VALUES 
-('stock_sync', 30)
+INSERT INTO process_control (process_name, interval_minutes) VALUES
+('stock_sync', 30),
+('recommendation_rescore', 1440)
 ON CONFLICT (process_name) DO NOTHING;
-
--- Insert some sample data for testing (optional)
--- INSERT INTO stocks (symbol, name) VALUES 
--- ('AAPL', 'Apple Inc.'),
--- ('GOOGL', 'Alphabet Inc.'),
--- ('MSFT', 'Microsoft Corporation')
--- ON CONFLICT (symbol) DO NOTHING;
-
-SHOW TABLES;
\ No newline at end of file
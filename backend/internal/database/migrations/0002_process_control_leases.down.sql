@@ -0,0 +1,7 @@
+ALTER TABLE process_control DROP COLUMN IF EXISTS schedule;
+ALTER TABLE process_control DROP COLUMN IF EXISTS enabled;
+ALTER TABLE process_control DROP COLUMN IF EXISTS heartbeat_at;
+ALTER TABLE process_control DROP COLUMN IF EXISTS lease_expires_at;
+ALTER TABLE process_control DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE process_control DROP COLUMN IF EXISTS checkpoint_updated_at;
+ALTER TABLE process_control DROP COLUMN IF EXISTS checkpoint;
@@ -0,0 +1,14 @@
+-- Pagination checkpoint of the last successfully processed page, used to resume interrupted runs
+ALTER TABLE process_control ADD COLUMN IF NOT EXISTS checkpoint TEXT;
+ALTER TABLE process_control ADD COLUMN IF NOT EXISTS checkpoint_updated_at TIMESTAMP;
+
+-- Lease-based locking: the owning instance heartbeats to extend lease_expires_at,
+-- and a lock whose lease has expired can be reclaimed by another instance
+ALTER TABLE process_control ADD COLUMN IF NOT EXISTS owner_id VARCHAR(255);
+ALTER TABLE process_control ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP;
+ALTER TABLE process_control ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP;
+
+-- Scheduler settings: disabled processes are never triggered automatically, and a cron
+-- schedule (evaluated in UTC) takes precedence over interval_minutes when set
+ALTER TABLE process_control ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE process_control ADD COLUMN IF NOT EXISTS schedule VARCHAR(100);
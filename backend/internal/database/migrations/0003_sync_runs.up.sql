@@ -0,0 +1,20 @@
+-- Every stock sync invocation with its counters
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id SERIAL PRIMARY KEY,
+    process_name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    resumed BOOLEAN NOT NULL DEFAULT FALSE,
+    resumed_from TEXT,
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP,
+    pages_fetched INT NOT NULL DEFAULT 0,
+    stocks_created INT NOT NULL DEFAULT 0,
+    stocks_updated INT NOT NULL DEFAULT 0,
+    analyses_inserted INT NOT NULL DEFAULT 0,
+    analyses_updated INT NOT NULL DEFAULT 0,
+    analyses_unchanged INT NOT NULL DEFAULT 0,
+    analyses_failed INT NOT NULL DEFAULT 0,
+    error_message TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_runs_started_at ON sync_runs(started_at DESC);
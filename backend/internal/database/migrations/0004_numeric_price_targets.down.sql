@@ -0,0 +1,4 @@
+ALTER TABLE stock_analysis DROP COLUMN IF EXISTS target_parse_status;
+ALTER TABLE stock_analysis DROP COLUMN IF EXISTS target_currency;
+ALTER TABLE stock_analysis DROP COLUMN IF EXISTS target_to_value;
+ALTER TABLE stock_analysis DROP COLUMN IF EXISTS target_from_value;
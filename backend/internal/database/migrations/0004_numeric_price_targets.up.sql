@@ -0,0 +1,7 @@
+-- Numeric price targets parsed from target_from/target_to at ingestion.
+-- target_parse_status is 'ok', 'empty' or 'invalid'; 'pending' rows predate
+-- parsing and are filled in by this migration's data step.
+ALTER TABLE stock_analysis ADD COLUMN IF NOT EXISTS target_from_value DECIMAL(18,4);
+ALTER TABLE stock_analysis ADD COLUMN IF NOT EXISTS target_to_value DECIMAL(18,4);
+ALTER TABLE stock_analysis ADD COLUMN IF NOT EXISTS target_currency VARCHAR(3);
+ALTER TABLE stock_analysis ADD COLUMN IF NOT EXISTS target_parse_status VARCHAR(10) NOT NULL DEFAULT 'pending';
@@ -0,0 +1 @@
+ALTER TABLE stock_analysis DROP COLUMN IF EXISTS action_type;
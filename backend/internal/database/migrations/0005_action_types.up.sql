@@ -0,0 +1,4 @@
+-- Action type classified from the free-text action at ingestion (see
+-- internal/actions). NULL rows predate classification and are filled in by
+-- this migration's data step.
+ALTER TABLE stock_analysis ADD COLUMN IF NOT EXISTS action_type VARCHAR(20);
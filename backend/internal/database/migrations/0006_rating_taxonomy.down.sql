@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rating_mappings;
+ALTER TABLE stock_analysis DROP COLUMN IF EXISTS rating_to_canonical;
+ALTER TABLE stock_analysis DROP COLUMN IF EXISTS rating_from_canonical;
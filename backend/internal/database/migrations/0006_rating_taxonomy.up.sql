@@ -0,0 +1,62 @@
+-- Canonical ratings on a 1-5 scale (1 Strong Sell .. 5 Strong Buy), resolved
+-- from rating_from/rating_to through rating_mappings. NULL means the raw
+-- rating is empty or has no mapping yet.
+ALTER TABLE stock_analysis ADD COLUMN IF NOT EXISTS rating_from_canonical SMALLINT;
+ALTER TABLE stock_analysis ADD COLUMN IF NOT EXISTS rating_to_canonical SMALLINT;
+
+-- Create rating_mappings table translating brokerage rating vocabularies to
+-- the canonical scale. raw_rating is stored normalized (lower case, single
+-- spaces); an empty brokerage applies to every brokerage, and a mapping for a
+-- specific brokerage takes precedence over it.
+CREATE TABLE IF NOT EXISTS rating_mappings (
+    id SERIAL PRIMARY KEY,
+    brokerage VARCHAR(100) NOT NULL DEFAULT '',
+    raw_rating VARCHAR(50) NOT NULL,
+    canonical_value SMALLINT NOT NULL CHECK (canonical_value BETWEEN 1 AND 5),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(brokerage, raw_rating)
+);
+
+-- Seed rating mappings with the common brokerage vocabularies
+INSERT INTO rating_mappings (brokerage, raw_rating, canonical_value) VALUES
+('', 'strong buy', 5),
+('', 'strong-buy', 5),
+('', 'conviction buy', 5),
+('', 'top pick', 5),
+('', 'strong outperform', 5),
+('', 'buy', 4),
+('', 'outperform', 4),
+('', 'overweight', 4),
+('', 'accumulate', 4),
+('', 'add', 4),
+('', 'positive', 4),
+('', 'moderate buy', 4),
+('', 'speculative buy', 4),
+('', 'market outperform', 4),
+('', 'sector outperform', 4),
+('', 'outperformer', 4),
+('', 'hold', 3),
+('', 'neutral', 3),
+('', 'equal weight', 3),
+('', 'equal-weight', 3),
+('', 'market perform', 3),
+('', 'sector perform', 3),
+('', 'peer perform', 3),
+('', 'in-line', 3),
+('', 'inline', 3),
+('', 'market weight', 3),
+('', 'sector weight', 3),
+('', 'mixed', 3),
+('', 'underperform', 2),
+('', 'underweight', 2),
+('', 'reduce', 2),
+('', 'negative', 2),
+('', 'moderate sell', 2),
+('', 'market underperform', 2),
+('', 'sector underperform', 2),
+('', 'underperformer', 2),
+('', 'sell', 1),
+('', 'strong sell', 1),
+('', 'strong-sell', 1)
+ON CONFLICT (brokerage, raw_rating) DO NOTHING;
@@ -0,0 +1,13 @@
+-- Move archived analyses back before dropping the archive
+INSERT INTO stock_analysis (id, stock_id, target_from, target_to, target_from_value, target_to_value,
+    target_currency, target_parse_status, action, action_type, brokerage, rating_from, rating_to,
+    rating_from_canonical, rating_to_canonical, analysis_date, created_at)
+SELECT id, stock_id, target_from, target_to, target_from_value, target_to_value,
+    target_currency, target_parse_status, action, action_type, brokerage, rating_from, rating_to,
+    rating_from_canonical, rating_to_canonical, analysis_date, created_at
+FROM stock_analysis_archive
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS stock_analysis_archive;
+
+DELETE FROM process_control WHERE process_name = 'analysis_retention';
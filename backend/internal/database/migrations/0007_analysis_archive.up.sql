@@ -0,0 +1,32 @@
+-- Analyses moved out of stock_analysis by the retention policy. Rows keep
+-- their original id; any column added to stock_analysis must be added here
+-- too.
+CREATE TABLE IF NOT EXISTS stock_analysis_archive (
+    id INT PRIMARY KEY,
+    stock_id INT REFERENCES stocks(id),
+    target_from VARCHAR(20),
+    target_to VARCHAR(20),
+    target_from_value DECIMAL(18,4),
+    target_to_value DECIMAL(18,4),
+    target_currency VARCHAR(3),
+    target_parse_status VARCHAR(10) NOT NULL DEFAULT 'pending',
+    action VARCHAR(100),
+    action_type VARCHAR(20),
+    brokerage VARCHAR(100),
+    rating_from VARCHAR(50),
+    rating_to VARCHAR(50),
+    rating_from_canonical SMALLINT,
+    rating_to_canonical SMALLINT,
+    analysis_date TIMESTAMP NOT NULL,
+    created_at TIMESTAMP,
+    archived_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- An analysis re-ingested after being archived is archived onto the same row
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_analysis_archive_unique ON stock_analysis_archive(stock_id, analysis_date, brokerage);
+
+-- Daily job applying the retention policy to every stock, so analyses also
+-- age out of stocks that are no longer updated
+INSERT INTO process_control (process_name, interval_minutes) VALUES
+('analysis_retention', 1440)
+ON CONFLICT (process_name) DO NOTHING;
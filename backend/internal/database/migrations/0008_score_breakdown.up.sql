@@ -0,0 +1,6 @@
+-- Per-factor breakdown of each score as a JSON array of
+-- {"factor", "score", "explanation"}, one entry per registered factor (see
+-- internal/scoring). The per-factor columns are kept for the built-in
+-- factors. Scores stored before this column existed get a breakdown on their
+-- next rescore.
+ALTER TABLE recommendation_scores ADD COLUMN IF NOT EXISTS breakdown JSONB;
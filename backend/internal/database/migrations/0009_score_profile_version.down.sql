@@ -0,0 +1 @@
+ALTER TABLE recommendation_scores DROP COLUMN IF EXISTS profile_version;
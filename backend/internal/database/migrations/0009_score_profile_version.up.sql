@@ -0,0 +1,3 @@
+-- Version of the scoring profile (see internal/scoring) each score was
+-- computed with. NULL for scores stored before profiles existed.
+ALTER TABLE recommendation_scores ADD COLUMN IF NOT EXISTS profile_version VARCHAR(50);
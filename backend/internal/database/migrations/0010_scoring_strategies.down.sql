@@ -0,0 +1,8 @@
+DELETE FROM recommendation_scores WHERE strategy <> 'default';
+
+CREATE UNIQUE INDEX IF NOT EXISTS recommendation_scores_stock_id_key ON recommendation_scores(stock_id);
+
+DROP INDEX IF EXISTS recommendation_scores@idx_recommendation_scores_strategy_total_score;
+DROP INDEX IF EXISTS recommendation_scores@idx_recommendation_scores_stock_strategy CASCADE;
+
+ALTER TABLE recommendation_scores DROP COLUMN IF EXISTS strategy;
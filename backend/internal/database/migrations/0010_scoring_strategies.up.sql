@@ -0,0 +1,11 @@
+-- Scores are stored per named strategy (see internal/scoring), so a stock has
+-- one row per strategy. Existing rows were computed by what is now the
+-- default strategy.
+ALTER TABLE recommendation_scores ADD COLUMN IF NOT EXISTS strategy VARCHAR(30) NOT NULL DEFAULT 'default';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recommendation_scores_stock_strategy ON recommendation_scores(stock_id, strategy);
+CREATE INDEX IF NOT EXISTS idx_recommendation_scores_strategy_total_score ON recommendation_scores(strategy, total_score DESC);
+
+-- The UNIQUE(stock_id) constraint of the initial schema is replaced by the
+-- index above
+DROP INDEX IF EXISTS recommendation_scores@recommendation_scores_stock_id_key CASCADE;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS brokerage_stats;
+DROP TABLE IF EXISTS stock_prices;
+
+DELETE FROM process_control WHERE process_name = 'brokerage_stats';
@@ -0,0 +1,30 @@
+-- Daily closing prices, used to check whether rating changes were right.
+-- Nothing in the API fills this table; load a price feed into it to get
+-- brokerage hit rates.
+CREATE TABLE IF NOT EXISTS stock_prices (
+    stock_id INT NOT NULL REFERENCES stocks(id),
+    price_date DATE NOT NULL,
+    close_price DECIMAL(18,4) NOT NULL,
+    PRIMARY KEY (stock_id, price_date)
+);
+
+-- Each brokerage's track record, recomputed by the brokerage_stats process
+-- from live and archived analyses. Credibility weights are derived from it
+-- with the active scoring profile.
+CREATE TABLE IF NOT EXISTS brokerage_stats (
+    brokerage VARCHAR(100) PRIMARY KEY,
+    analyses_count INT NOT NULL DEFAULT 0,
+    stocks_covered INT NOT NULL DEFAULT 0,
+    upgrades INT NOT NULL DEFAULT 0,
+    downgrades INT NOT NULL DEFAULT 0,
+    evaluated_calls INT NOT NULL DEFAULT 0,
+    hits INT NOT NULL DEFAULT 0,
+    horizon_days INT NOT NULL,
+    first_analysis_at TIMESTAMP,
+    last_analysis_at TIMESTAMP,
+    computed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO process_control (process_name, interval_minutes) VALUES
+('brokerage_stats', 1440)
+ON CONFLICT (process_name) DO NOTHING;
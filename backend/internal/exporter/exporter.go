@@ -0,0 +1,173 @@
+// Package exporter encodes dataset export rows as CSV, NDJSON or Parquet one
+// row at a time, so an export never holds the full dataset in memory.
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"stock-api/internal/models"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// parquetRowGroupSize bounds the rows buffered before a Parquet row group is
+// written out.
+const parquetRowGroupSize = 10000
+
+// Formats lists the supported export formats.
+var Formats = []string{FormatCSV, FormatNDJSON, FormatParquet}
+
+// RowWriter encodes export rows. Close must be called to flush the output;
+// for Parquet it also writes the file footer.
+type RowWriter interface {
+	Write(row models.ExportRow) error
+	Close() error
+}
+
+// NewRowWriter returns a writer for format that writes to w.
+func NewRowWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return &parquetWriter{
+			writer: parquet.NewGenericWriter[models.ExportRow](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize)),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// ContentType returns the media type of an export format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+var csvHeader = []string{
+	"stock_id", "symbol", "company", "analysis_id", "brokerage", "action", "action_type", "rating_from", "rating_to",
+	"rating_from_canonical", "rating_to_canonical", "target_from", "target_to", "target_from_value", "target_to_value", "target_currency", "target_parse_status", "analysis_date", "total_score", "rating_score", "rating_change_score",
+	"target_change_score", "action_score", "coverage_score", "confidence", "reason", "score_calculated_at", "score_profile_version",
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+func (c *csvWriter) Write(row models.ExportRow) error {
+	return c.writer.Write([]string{
+		strconv.Itoa(row.StockID),
+		row.Symbol,
+		row.Company,
+		intField(row.AnalysisID),
+		stringField(row.Brokerage),
+		stringField(row.Action),
+		stringField(row.ActionType),
+		stringField(row.RatingFrom),
+		stringField(row.RatingTo),
+		intField(row.RatingFromCanonical),
+		intField(row.RatingToCanonical),
+		stringField(row.TargetFrom),
+		stringField(row.TargetTo),
+		floatField(row.TargetFromValue),
+		floatField(row.TargetToValue),
+		stringField(row.TargetCurrency),
+		stringField(row.TargetParseStatus),
+		timeField(row.AnalysisDate),
+		floatField(row.TotalScore),
+		floatField(row.RatingScore),
+		floatField(row.RatingChangeScore),
+		floatField(row.TargetChangeScore),
+		floatField(row.ActionScore),
+		floatField(row.CoverageScore),
+		stringField(row.Confidence),
+		stringField(row.Reason),
+		timeField(row.ScoreCalculatedAt),
+		stringField(row.ScoreProfileVersion),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(row models.ExportRow) error {
+	return n.encoder.Encode(row)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+type parquetWriter struct {
+	writer *parquet.GenericWriter[models.ExportRow]
+}
+
+func (p *parquetWriter) Write(row models.ExportRow) error {
+	_, err := p.writer.Write([]models.ExportRow{row})
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.writer.Close()
+}
+
+func intField(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func stringField(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func floatField(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+func timeField(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,60 @@
+package fakekarenai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"stock-api/internal/clients"
+)
+
+// LoadFixtures reads items from a JSON file or from every *.json file in a
+// directory, in file name order. A file holds either an array of items or a
+// KarenAI page object with an "items" array.
+func LoadFixtures(path string) ([]clients.StockAnalysis, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fixtures: %w", err)
+		}
+		sort.Strings(files)
+	}
+
+	var items []clients.StockAnalysis
+	for _, file := range files {
+		fileItems, err := loadFixtureFile(file)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, fileItems...)
+	}
+
+	return items, nil
+}
+
+func loadFixtureFile(file string) ([]clients.StockAnalysis, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture %s: %w", file, err)
+	}
+
+	var items []clients.StockAnalysis
+	if err := json.Unmarshal(data, &items); err == nil {
+		return items, nil
+	}
+
+	var page clients.StockListResponse
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", file, err)
+	}
+
+	return page.Items, nil
+}
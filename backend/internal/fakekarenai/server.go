@@ -0,0 +1,197 @@
+// Package fakekarenai is a stand-in for the KarenAI /list endpoint. It serves
+// fixture items with next_page pagination and can inject latency, rate
+// limits, server errors and malformed pages. Server is an http.Handler, so it
+// can back cmd/fake-karenai or an httptest.Server.
+package fakekarenai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"stock-api/internal/clients"
+)
+
+const defaultPageSize = 10
+
+// Options controls pagination and fault injection. Faults are deterministic:
+// an "every N" option fires on every Nth request to /list, counting from the
+// first request the server receives.
+type Options struct {
+	// PageSize is the number of items per page (default 10).
+	PageSize int
+	// Token, when set, must be sent as "Authorization: Bearer <Token>".
+	Token string
+	// Latency delays every response.
+	Latency time.Duration
+
+	// RateLimitEvery answers every Nth request with 429.
+	RateLimitEvery int
+	// RetryAfter is sent with injected 429s, in whole seconds.
+	RetryAfter time.Duration
+	// ServerErrorEvery answers every Nth request with 500.
+	ServerErrorEvery int
+	// MalformedEvery answers every Nth request with a truncated JSON body.
+	MalformedEvery int
+}
+
+// Stats counts what the server has answered so far.
+type Stats struct {
+	Requests     int `json:"requests"`
+	Pages        int `json:"pages"`
+	RateLimited  int `json:"rate_limited"`
+	ServerErrors int `json:"server_errors"`
+	Malformed    int `json:"malformed"`
+	Unauthorized int `json:"unauthorized"`
+}
+
+// Server serves a fixed list of items as KarenAI pages.
+type Server struct {
+	opts  Options
+	pages map[string]clients.StockListResponse
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewServer paginates items in order. Like KarenAI, next_page is the ticker
+// of the last item on the current page.
+func NewServer(items []clients.StockAnalysis, opts Options) *Server {
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+
+	s := &Server{
+		opts:  opts,
+		pages: make(map[string]clients.StockListResponse),
+	}
+
+	token := ""
+	start := 0
+	for {
+		end := start + opts.PageSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		page := clients.StockListResponse{Items: items[start:end]}
+		if end < len(items) {
+			page.NextPage = s.uniqueToken(items[end-1].Ticker)
+		}
+		s.pages[token] = page
+
+		if page.NextPage == "" {
+			break
+		}
+		token = page.NextPage
+		start = end
+	}
+
+	return s
+}
+
+// uniqueToken keeps tokens distinct when a ticker ends more than one page.
+func (s *Server) uniqueToken(ticker string) string {
+	token := ticker
+	for i := 2; ; i++ {
+		if _, taken := s.pages[token]; !taken && token != "" {
+			return token
+		}
+		token = fmt.Sprintf("%s-%d", ticker, i)
+	}
+}
+
+// Stats returns a copy of the request counters.
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// PageCount is the number of pages a full walk of the server returns.
+func (s *Server) PageCount() int {
+	return len(s.pages)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, "/list") {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	s.stats.Requests++
+	n := s.stats.Requests
+	s.mu.Unlock()
+
+	if s.opts.Latency > 0 {
+		select {
+		case <-time.After(s.opts.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if s.opts.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.opts.Token {
+		s.count(func(st *Stats) { st.Unauthorized++ })
+		writeJSONError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	switch {
+	case fires(s.opts.RateLimitEvery, n):
+		s.count(func(st *Stats) { st.RateLimited++ })
+		if s.opts.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(s.opts.RetryAfter/time.Second)))
+		}
+		writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	case fires(s.opts.ServerErrorEvery, n):
+		s.count(func(st *Stats) { st.ServerErrors++ })
+		writeJSONError(w, http.StatusInternalServerError, "injected server error")
+		return
+	}
+
+	page, ok := s.pages[r.URL.Query().Get("next_page")]
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "unknown next_page")
+		return
+	}
+
+	body, err := json.Marshal(page)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if fires(s.opts.MalformedEvery, n) {
+		s.count(func(st *Stats) { st.Malformed++ })
+		body = body[:len(body)/2]
+	} else {
+		s.count(func(st *Stats) { st.Pages++ })
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (s *Server) count(update func(*Stats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.stats)
+}
+
+func fires(every, n int) bool {
+	return every > 0 && n%every == 0
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,306 @@
+// Package importer reads analyst rating files in CSV or JSON Lines format and
+// validates every row into the records the sync ingests.
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"stock-api/internal/models"
+	"stock-api/internal/pricing"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Fields are the import fields, named like the JSON fields of
+// clients.StockAnalysis.
+var Fields = []string{"ticker", "target_from", "target_to", "company", "action", "brokerage", "rating_from", "rating_to", "time"}
+
+// fieldLimits mirrors the column sizes of stocks and stock_analysis in
+// internal/database/migrations.
+var fieldLimits = map[string]int{
+	"ticker":      10,
+	"target_from": 20,
+	"target_to":   20,
+	"company":     255,
+	"action":      100,
+	"brokerage":   100,
+	"rating_from": 50,
+	"rating_to":   50,
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ErrInvalidFile is returned for problems that stop the whole import, such
+// as an unknown format or a CSV header without a required column.
+var ErrInvalidFile = errors.New("invalid import file")
+
+// Options controls how a file is read.
+type Options struct {
+	// Format is FormatCSV or FormatJSONL.
+	Format string
+	// Mapping maps an import field to the column (CSV) or key (JSONL) that
+	// holds it in the file. Unmapped fields are read from a column named
+	// like the field.
+	Mapping map[string]string
+}
+
+// Row is one data row of the file. Line is its 1-based line number in the
+// file. Record is only meaningful when Errors is empty.
+type Row struct {
+	Line   int
+	Record models.AnalysisRecord
+	Errors []string
+}
+
+// DetectFormat guesses the format from a file name or content type, and
+// returns an empty string when neither gives it away.
+func DetectFormat(filename, contentType string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	}
+
+	switch {
+	case strings.Contains(contentType, "csv"):
+		return FormatCSV
+	case strings.Contains(contentType, "ndjson"), strings.Contains(contentType, "jsonl"):
+		return FormatJSONL
+	}
+
+	return ""
+}
+
+// ParseMapping parses "field=column" pairs separated by commas, e.g.
+// "ticker=Symbol,time=Date".
+func ParseMapping(spec string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	if strings.TrimSpace(spec) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		column = strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("%w: mapping %q must look like field=column", ErrInvalidFile, pair)
+		}
+		if _, known := fieldLimits[field]; !known && field != "time" {
+			return nil, fmt.Errorf("%w: unknown field %q in mapping, expected one of %s", ErrInvalidFile, field, strings.Join(Fields, ", "))
+		}
+		mapping[field] = column
+	}
+
+	return mapping, nil
+}
+
+// Read streams the rows of r to fn in file order, stopping at the first
+// error fn returns.
+func Read(r io.Reader, opts Options, fn func(Row) error) error {
+	switch opts.Format {
+	case FormatCSV:
+		return readCSV(r, opts, fn)
+	case FormatJSONL:
+		return readJSONL(r, opts, fn)
+	default:
+		return fmt.Errorf("%w: unsupported format %q, expected %s or %s", ErrInvalidFile, opts.Format, FormatCSV, FormatJSONL)
+	}
+}
+
+func sourceName(opts Options, field string) string {
+	if column, ok := opts.Mapping[field]; ok {
+		return column
+	}
+	return field
+}
+
+func readCSV(r io.Reader, opts Options, fn func(Row) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to read CSV header: %v", ErrInvalidFile, err)
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, column := range header {
+		positions[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))] = i
+	}
+
+	columns := make(map[string]int)
+	for _, field := range Fields {
+		if i, ok := positions[strings.ToLower(sourceName(opts, field))]; ok {
+			columns[field] = i
+		}
+	}
+	for _, field := range []string{"ticker", "time"} {
+		if _, ok := columns[field]; !ok {
+			return fmt.Errorf("%w: CSV header has no %q column for field %s", ErrInvalidFile, sourceName(opts, field), field)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return fmt.Errorf("failed to read CSV: %w", err)
+			}
+			if err := fn(Row{Line: parseErr.StartLine, Errors: []string{parseErr.Err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		values := make(map[string]string, len(columns))
+		for field, i := range columns {
+			if i < len(record) {
+				values[field] = record[i]
+			}
+		}
+
+		if err := fn(buildRow(line, values)); err != nil {
+			return err
+		}
+	}
+}
+
+func readJSONL(r io.Reader, opts Options, fn func(Row) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &object); err != nil {
+			if err := fn(Row{Line: line, Errors: []string{"invalid JSON: " + err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		values := make(map[string]string, len(Fields))
+		for _, field := range Fields {
+			if value, ok := object[sourceName(opts, field)]; ok && value != nil {
+				values[field] = stringValue(value)
+			}
+		}
+
+		if err := fn(buildRow(line, values)); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read JSON Lines: %w", err)
+	}
+
+	return nil
+}
+
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// buildRow validates the raw field values of one row.
+func buildRow(line int, values map[string]string) Row {
+	row := Row{Line: line}
+	for field, value := range values {
+		values[field] = strings.TrimSpace(value)
+	}
+
+	for _, field := range Fields {
+		limit, ok := fieldLimits[field]
+		if ok && len(values[field]) > limit {
+			row.Errors = append(row.Errors, fmt.Sprintf("%s is longer than %d characters", field, limit))
+		}
+	}
+
+	if values["ticker"] == "" {
+		row.Errors = append(row.Errors, "ticker is required")
+	}
+
+	for _, field := range []string{"target_from", "target_to"} {
+		if values[field] != "" && !validPrice(values[field]) {
+			row.Errors = append(row.Errors, fmt.Sprintf("%s %q is not a price", field, values[field]))
+		}
+	}
+
+	var analysisDate time.Time
+	if values["time"] == "" {
+		row.Errors = append(row.Errors, "time is required")
+	} else if parsed, err := parseTime(values["time"]); err != nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("time %q is not a valid date", values["time"]))
+	} else {
+		analysisDate = parsed
+	}
+
+	row.Record = models.AnalysisRecord{
+		Symbol:       strings.ToUpper(values["ticker"]),
+		Company:      values["company"],
+		TargetFrom:   values["target_from"],
+		TargetTo:     values["target_to"],
+		Action:       values["action"],
+		Brokerage:    values["brokerage"],
+		RatingFrom:   values["rating_from"],
+		RatingTo:     values["rating_to"],
+		AnalysisDate: analysisDate,
+	}
+
+	return row
+}
+
+func validPrice(value string) bool {
+	_, ok := pricing.ParsePrice(value)
+	return ok
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time format")
+}
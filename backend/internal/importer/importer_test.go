@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := ParseMapping(" ticker = Symbol ,time=Date")
+	if err != nil {
+		t.Fatalf("ParseMapping: %v", err)
+	}
+	want := map[string]string{"ticker": "Symbol", "time": "Date"}
+	if !reflect.DeepEqual(mapping, want) {
+		t.Fatalf("got %v, want %v", mapping, want)
+	}
+
+	if mapping, err := ParseMapping("  "); err != nil || len(mapping) != 0 {
+		t.Fatalf("empty spec gave %v, %v", mapping, err)
+	}
+
+	for _, spec := range []string{"ticker", "ticker=", "=Symbol", "price=Target", "ticker=Symbol,,time=Date"} {
+		if _, err := ParseMapping(spec); !errors.Is(err, ErrInvalidFile) {
+			t.Errorf("ParseMapping(%q) error = %v, want %v", spec, err, ErrInvalidFile)
+		}
+	}
+}
+
+func readRows(t *testing.T, input string, opts Options) []Row {
+	t.Helper()
+
+	var rows []Row
+	err := Read(strings.NewReader(input), opts, func(row Row) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return rows
+}
+
+func TestReadCSVLineNumbers(t *testing.T) {
+	input := "\ufeffSymbol,Date,Company\n" +
+		"aapl,2025-07-01,Apple\n" +
+		"\n" +
+		"MSFT,2025-07-02,\"Microsoft\nCorporation\"\n" +
+		"NVDA,2025-07-03,\"Nvidia\n" +
+		"TSLA,not a date,Tesla\n"
+
+	rows := readRows(t, input, Options{Format: FormatCSV, Mapping: map[string]string{"ticker": "symbol", "time": "date"}})
+
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3: %+v", len(rows), rows)
+	}
+
+	if rows[0].Line != 2 || len(rows[0].Errors) != 0 || rows[0].Record.Symbol != "AAPL" {
+		t.Errorf("row 1 = %+v", rows[0])
+	}
+	if rows[1].Line != 4 || len(rows[1].Errors) != 0 || rows[1].Record.Company != "Microsoft\nCorporation" {
+		t.Errorf("row 2 = %+v", rows[1])
+	}
+	// The unterminated quote swallows the rest of the file into one bad row
+	if rows[2].Line != 6 || len(rows[2].Errors) == 0 {
+		t.Errorf("row 3 = %+v", rows[2])
+	}
+}
+
+func TestReadCSVRequiresTickerAndTimeColumns(t *testing.T) {
+	err := Read(strings.NewReader("ticker,company\nAAPL,Apple\n"), Options{Format: FormatCSV}, func(Row) error { return nil })
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidFile)
+	}
+}
+
+func TestReadJSONLLineNumbers(t *testing.T) {
+	input := `{"ticker":"AAPL","time":"2025-07-01T10:00:00Z","target_to":"$200"}` + "\n" +
+		"\n" +
+		`{"ticker":"MSFT",` + "\n" +
+		`{"Symbol":"NVDA","time":"2025-07-03","target_to":12.5}` + "\n"
+
+	rows := readRows(t, input, Options{Format: FormatJSONL, Mapping: map[string]string{"ticker": "Symbol"}})
+
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3: %+v", len(rows), rows)
+	}
+
+	// AAPL has no Symbol key under the mapping
+	if rows[0].Line != 1 || !reflect.DeepEqual(rows[0].Errors, []string{"ticker is required"}) {
+		t.Errorf("row 1 = %+v", rows[0])
+	}
+	if rows[1].Line != 3 || len(rows[1].Errors) != 1 || !strings.HasPrefix(rows[1].Errors[0], "invalid JSON") {
+		t.Errorf("row 2 = %+v", rows[1])
+	}
+	if rows[2].Line != 4 || len(rows[2].Errors) != 0 || rows[2].Record.Symbol != "NVDA" || rows[2].Record.TargetTo != "12.5" {
+		t.Errorf("row 3 = %+v", rows[2])
+	}
+}
+
+func TestReadRejectsUnknownFormat(t *testing.T) {
+	err := Read(strings.NewReader(""), Options{Format: "xml"}, func(Row) error { return nil })
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidFile)
+	}
+}
+
+func TestBuildRow(t *testing.T) {
+	row := buildRow(7, map[string]string{
+		"ticker":    " msft ",
+		"time":      "2025-07-01 09:30:00",
+		"target_to": "$450.00",
+		"brokerage": " Jefferies ",
+	})
+	if len(row.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", row.Errors)
+	}
+	if row.Line != 7 || row.Record.Symbol != "MSFT" || row.Record.Brokerage != "Jefferies" {
+		t.Errorf("row = %+v", row)
+	}
+	if want := time.Date(2025, 7, 1, 9, 30, 0, 0, time.UTC); !row.Record.AnalysisDate.Equal(want) {
+		t.Errorf("analysis date = %v, want %v", row.Record.AnalysisDate, want)
+	}
+
+	row = buildRow(8, map[string]string{
+		"ticker":      strings.Repeat("X", 11),
+		"time":        "July 1st",
+		"target_from": "1e5",
+	})
+	want := []string{
+		"ticker is longer than 10 characters",
+		`target_from "1e5" is not a price`,
+		`time "July 1st" is not a valid date`,
+	}
+	if !reflect.DeepEqual(row.Errors, want) {
+		t.Errorf("errors = %q, want %q", row.Errors, want)
+	}
+
+	row = buildRow(9, map[string]string{})
+	if want := []string{"ticker is required", "time is required"}; !reflect.DeepEqual(row.Errors, want) {
+		t.Errorf("errors = %q, want %q", row.Errors, want)
+	}
+}
@@ -18,10 +18,24 @@ type StockAnalysis struct {
 	StockID    int       `json:"stock_id"`
 	TargetFrom string    `json:"target_from"`
 	TargetTo   string    `json:"target_to"`
+	// Numeric targets parsed at ingestion; nil when absent or unparseable
+	TargetFromValue   *float64 `json:"target_from_value"`
+	TargetToValue     *float64 `json:"target_to_value"`
+	TargetCurrency    string   `json:"target_currency,omitempty"`
+	TargetParseStatus string   `json:"target_parse_status"`
 	Action     string    `json:"action"`
+	// ActionType is Action classified by the actions package
+	ActionType string    `json:"action_type"`
 	Brokerage  string    `json:"brokerage"`
 	RatingFrom string    `json:"rating_from"`
 	RatingTo   string    `json:"rating_to"`
+	// Canonical 1-5 ratings resolved through rating_mappings; nil when unmapped
+	RatingFromCanonical *int   `json:"rating_from_canonical"`
+	RatingToCanonical   *int   `json:"rating_to_canonical"`
+	RatingFromLabel     string `json:"rating_from_label,omitempty"`
+	RatingToLabel       string `json:"rating_to_label,omitempty"`
+	// Archived is set on analyses read from stock_analysis_archive
+	Archived bool `json:"archived,omitempty"`
 	AnalysisDate time.Time `json:"analysis_date"`
 	CreatedAt  time.Time `json:"created_at"`
 }
@@ -29,6 +43,8 @@ type StockAnalysis struct {
 type StockWithAnalysis struct {
 	Stock
 	LatestAnalysis []StockAnalysis `json:"latest_analysis,omitempty"`
+	// ArchivedAnalysis is only loaded when archived history is requested
+	ArchivedAnalysis []StockAnalysis `json:"archived_analysis,omitempty"`
 }
 
 type StockRecommendation struct {
@@ -36,11 +52,17 @@ type StockRecommendation struct {
 	Score      float64           `json:"score"`
 	Reason     string            `json:"reason"`
 	Confidence string            `json:"confidence"`
+	Breakdown  []FactorScore     `json:"breakdown,omitempty"`
+	// ProfileVersion is the scoring profile the score was computed with
+	ProfileVersion string `json:"profile_version,omitempty"`
+	Strategy       string `json:"strategy,omitempty"`
 }
 
 type RecommendationScore struct {
 	ID                 int     `json:"id"`
 	StockID            int     `json:"stock_id"`
+	// Strategy is the named scoring strategy that produced the score
+	Strategy           string  `json:"strategy"`
 	TotalScore         float64 `json:"total_score"`
 	RatingScore        float64 `json:"rating_score"`
 	RatingChangeScore  float64 `json:"rating_change_score"`
@@ -50,11 +72,52 @@ type RecommendationScore struct {
 	Confidence         string  `json:"confidence"`
 	Reason             string  `json:"reason"`
 	LatestAnalysisID   *int    `json:"latest_analysis_id,omitempty"`
+	// Breakdown has one entry per registered scoring factor
+	Breakdown          []FactorScore `json:"breakdown"`
+	// ProfileVersion is the version of the scoring profile that produced
+	// the score
+	ProfileVersion     string  `json:"profile_version"`
 	CalculatedAt       time.Time `json:"calculated_at"`
 	CreatedAt          time.Time `json:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
 }
 
+// FactorScore is the contribution of one scoring factor to a total score.
+type FactorScore struct {
+	Factor      string  `json:"factor"`
+	Score       float64 `json:"score"`
+	Explanation string  `json:"explanation"`
+}
+
+// ScoringStrategy describes a strategy available to the recommendations.
+type ScoringStrategy struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Factors     []string `json:"factors"`
+	Default     bool     `json:"default"`
+}
+
+// BrokerageStats is a brokerage's track record and the credibility weight
+// derived from it. A call is a rating change; it is evaluated once closing
+// prices exist around it and HorizonDays after it, and is a hit when the
+// price moved the way the call pointed. HitRate is nil without evaluated
+// calls.
+type BrokerageStats struct {
+	Brokerage       string     `json:"brokerage"`
+	AnalysesCount   int        `json:"analyses_count"`
+	StocksCovered   int        `json:"stocks_covered"`
+	Upgrades        int        `json:"upgrades"`
+	Downgrades      int        `json:"downgrades"`
+	EvaluatedCalls  int        `json:"evaluated_calls"`
+	Hits            int        `json:"hits"`
+	HitRate         *float64   `json:"hit_rate"`
+	HorizonDays     int        `json:"horizon_days"`
+	FirstAnalysisAt *time.Time `json:"first_analysis_at,omitempty"`
+	LastAnalysisAt  *time.Time `json:"last_analysis_at,omitempty"`
+	Weight          float64    `json:"weight"`
+	ComputedAt      time.Time  `json:"computed_at"`
+}
+
 type RecommendationWithStock struct {
 	RecommendationScore
 	Stock StockWithAnalysis `json:"stock"`
@@ -66,10 +129,192 @@ type ProcessControl struct {
 	IsRunning      bool      `json:"is_running"`
 	LastExecution  *time.Time `json:"last_execution"`
 	IntervalMinutes int       `json:"interval_minutes"`
+	Checkpoint     string     `json:"checkpoint,omitempty"`
+	CheckpointUpdatedAt *time.Time `json:"checkpoint_updated_at,omitempty"`
+	OwnerID        string     `json:"owner_id,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	HeartbeatAt    *time.Time `json:"heartbeat_at,omitempty"`
+	LockStale      bool       `json:"lock_stale"`
+	Enabled        bool       `json:"enabled"`
+	Schedule       string     `json:"schedule,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
+type SchedulerStatus struct {
+	Enabled     bool                 `json:"enabled"`
+	InstanceID  string               `json:"instance_id"`
+	TickSeconds int                  `json:"tick_seconds"`
+	Jobs        []SchedulerJobStatus `json:"jobs"`
+}
+
+type SchedulerJobStatus struct {
+	Name            string     `json:"name"`
+	Registered      bool       `json:"registered"`
+	Enabled         bool       `json:"enabled"`
+	Schedule        string     `json:"schedule,omitempty"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	LastExecution   *time.Time `json:"last_execution"`
+	NextRunAt       *time.Time `json:"next_run_at"`
+	IsRunning       bool       `json:"is_running"`
+	LockOwner       string     `json:"lock_owner,omitempty"`
+	RunningHere     bool       `json:"running_here"`
+	LastRunAt       *time.Time `json:"last_run_at,omitempty"`
+	LastResult      string     `json:"last_result,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastDurationMs  int64      `json:"last_duration_ms,omitempty"`
+}
+
+// SchedulerJobUpdate changes how a process is scheduled. Nil fields are left
+// untouched; an empty Schedule falls back to interval_minutes.
+type SchedulerJobUpdate struct {
+	Enabled         *bool   `json:"enabled"`
+	Schedule        *string `json:"schedule"`
+	IntervalMinutes *int    `json:"interval_minutes"`
+}
+
+const (
+	SyncPhaseFetching  = "fetching"
+	SyncPhaseRescoring = "rescoring"
+)
+
+const (
+	SyncRunStatusRunning   = "running"
+	SyncRunStatusCompleted = "completed"
+	SyncRunStatusFailed    = "failed"
+	SyncRunStatusCancelled = "cancelled"
+	SyncRunStatusAbandoned = "abandoned"
+)
+
+type SyncRun struct {
+	ID                int        `json:"id"`
+	ProcessName       string     `json:"process_name"`
+	Status            string     `json:"status"`
+	Resumed           bool       `json:"resumed"`
+	ResumedFrom       string     `json:"resumed_from,omitempty"`
+	StartedAt         time.Time  `json:"started_at"`
+	FinishedAt        *time.Time `json:"finished_at"`
+	PagesFetched      int        `json:"pages_fetched"`
+	StocksCreated     int        `json:"stocks_created"`
+	StocksUpdated     int        `json:"stocks_updated"`
+	AnalysesInserted  int        `json:"analyses_inserted"`
+	AnalysesUpdated   int        `json:"analyses_updated"`
+	AnalysesUnchanged int        `json:"analyses_unchanged"`
+	AnalysesFailed    int        `json:"analyses_failed"`
+	ErrorMessage      string     `json:"error_message,omitempty"`
+}
+
+// SyncProgress is a live snapshot of the sync running in this process.
+type SyncProgress struct {
+	Running             bool       `json:"running"`
+	Phase               string     `json:"phase,omitempty"`
+	RunID               int        `json:"run_id,omitempty"`
+	Resumed             bool       `json:"resumed"`
+	StartedAt           *time.Time `json:"started_at,omitempty"`
+	PagesProcessed      int        `json:"pages_processed"`
+	ItemsProcessed      int        `json:"items_processed"`
+	Errors              int        `json:"errors"`
+	LastError           string     `json:"last_error,omitempty"`
+	EstimatedTotalPages int        `json:"estimated_total_pages,omitempty"`
+	ETASeconds          *int       `json:"eta_seconds,omitempty"`
+	ETA                 *time.Time `json:"eta,omitempty"`
+	CancelRequested     bool       `json:"cancel_requested"`
+}
+
+// AnalysisRecord is a single analyst rating change as received from a data
+// source, before it is split into stocks and stock_analysis rows.
+type AnalysisRecord struct {
+	Symbol       string    `json:"ticker"`
+	Company      string    `json:"company"`
+	TargetFrom   string    `json:"target_from"`
+	TargetTo     string    `json:"target_to"`
+	Action       string    `json:"action"`
+	Brokerage    string    `json:"brokerage"`
+	RatingFrom   string    `json:"rating_from"`
+	RatingTo     string    `json:"rating_to"`
+	AnalysisDate time.Time `json:"time"`
+}
+
+// IngestResult summarizes what a batch ingestion wrote.
+type IngestResult struct {
+	StocksCreated     int   `json:"stocks_created"`
+	StocksUpdated     int   `json:"stocks_updated"`
+	AnalysesInserted  int   `json:"analyses_inserted"`
+	AnalysesUpdated   int   `json:"analyses_updated"`
+	AnalysesUnchanged int   `json:"analyses_unchanged"`
+	AnalysesFailed    int   `json:"analyses_failed"`
+	AnalysesArchived  int   `json:"analyses_archived"`
+	// StockIDs are the stocks whose analyses were written or archived
+	StockIDs []int `json:"-"`
+}
+
+// Add accumulates the counters of another batch.
+func (r *IngestResult) Add(other *IngestResult) {
+	r.StocksCreated += other.StocksCreated
+	r.StocksUpdated += other.StocksUpdated
+	r.AnalysesInserted += other.AnalysesInserted
+	r.AnalysesUpdated += other.AnalysesUpdated
+	r.AnalysesUnchanged += other.AnalysesUnchanged
+	r.AnalysesFailed += other.AnalysesFailed
+	r.AnalysesArchived += other.AnalysesArchived
+}
+
+// ImportRowError lists why one row of an import file was rejected.
+type ImportRowError struct {
+	Line   int      `json:"line"`
+	Errors []string `json:"errors"`
+}
+
+// ImportReport is the outcome of a bulk import. Ingest is nil for dry runs.
+type ImportReport struct {
+	Format             string           `json:"format"`
+	DryRun             bool             `json:"dry_run"`
+	TotalRows          int              `json:"total_rows"`
+	ValidRows          int              `json:"valid_rows"`
+	InvalidRows        int              `json:"invalid_rows"`
+	RowErrors          []ImportRowError `json:"row_errors"`
+	RowErrorsTruncated bool             `json:"row_errors_truncated"`
+	Ingest             *IngestResult    `json:"ingest,omitempty"`
+	StocksRescored     int              `json:"stocks_rescored"`
+}
+
+// RatingMapping maps a raw rating, optionally of a single brokerage, to the
+// canonical 1-5 scale. An empty Brokerage applies to every brokerage.
+type RatingMapping struct {
+	ID             int       `json:"id"`
+	Brokerage      string    `json:"brokerage"`
+	RawRating      string    `json:"raw_rating"`
+	CanonicalValue int       `json:"canonical_value"`
+	CanonicalLabel string    `json:"canonical_label"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// RatingMappingInput creates or changes the mapping of a raw rating. An
+// empty Brokerage maps the rating for every brokerage.
+type RatingMappingInput struct {
+	Brokerage      string `json:"brokerage"`
+	RawRating      string `json:"raw_rating"`
+	CanonicalValue int    `json:"canonical_value"`
+}
+
+// UnmappedRating is a raw rating found on stored analyses that no mapping
+// resolves, with how often and how recently it was seen.
+type UnmappedRating struct {
+	Brokerage   string    `json:"brokerage"`
+	RawRating   string    `json:"raw_rating"`
+	Occurrences int       `json:"occurrences"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// RatingMappingChange reports the effect of editing the rating mappings on
+// stored analyses.
+type RatingMappingChange struct {
+	Mapping         *RatingMapping `json:"mapping,omitempty"`
+	AnalysesUpdated int            `json:"analyses_updated"`
+	StocksRescored  int            `json:"stocks_rescored"`
+}
+
 type PaginationRequest struct {
 	Page     int `json:"page"`
 	PageSize int `json:"page_size"`
@@ -89,10 +334,89 @@ type PaginatedResponse[T any] struct {
 	Meta PaginationMeta `json:"meta"`
 }
 
+// AnalysisTimelineParams filters and pages the analyses of one stock.
+type AnalysisTimelineParams struct {
+	// From and To bound analysis_date; To is exclusive
+	From      *time.Time
+	To        *time.Time
+	Brokerage string
+	// ActionType is one of the actions package types, or empty for all
+	ActionType      string
+	IncludeArchived bool
+	// After is the last analysis of the previous page, nil for the first one
+	After *AnalysisCursor
+	Limit int
+}
+
+// AnalysisCursor is the position of an analysis in a timeline, which is
+// ordered by analysis date and ID, newest first.
+type AnalysisCursor struct {
+	AnalysisDate time.Time
+	ID           int
+}
+
+// AnalysisTimelineEntry is an analysis with the change it records derived
+// from its raw values.
+type AnalysisTimelineEntry struct {
+	StockAnalysis
+	// RatingDelta is the canonical rating_to minus rating_from; nil unless
+	// both are mapped
+	RatingDelta *int `json:"rating_delta"`
+	// TargetChangePercent is the change from target_from to target_to in
+	// percent; nil unless both targets parsed and target_from is positive
+	TargetChangePercent *float64 `json:"target_change_percent"`
+}
+
+type AnalysisTimeline struct {
+	Symbol   string                  `json:"symbol"`
+	Analyses []AnalysisTimelineEntry `json:"analyses"`
+	// NextCursor fetches the next page; empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
 type StockFilterParams struct {
 	ActionType string `json:"action_type" query:"action_type"`
 	Brokerage  string `json:"brokerage" query:"brokerage"`
 	SortBy     string `json:"sort_by" query:"sort_by"`
+	// MinTarget and MaxTarget bound the numeric new price target of any
+	// analysis of the stock
+	MinTarget *float64 `json:"min_target,omitempty" query:"min_target"`
+	MaxTarget *float64 `json:"max_target,omitempty" query:"max_target"`
+}
+
+// ExportRow is one analysis of a stock, joined with the stock's current
+// recommendation score, as written by the dataset exports. Stocks without
+// analyses or without a score export their analysis or score fields as nulls.
+type ExportRow struct {
+	StockID             int        `json:"stock_id" parquet:"stock_id"`
+	Symbol              string     `json:"symbol" parquet:"symbol"`
+	Company             string     `json:"company" parquet:"company"`
+	AnalysisID          *int       `json:"analysis_id" parquet:"analysis_id"`
+	Brokerage           *string    `json:"brokerage" parquet:"brokerage"`
+	Action              *string    `json:"action" parquet:"action"`
+	ActionType          *string    `json:"action_type" parquet:"action_type"`
+	RatingFrom          *string    `json:"rating_from" parquet:"rating_from"`
+	RatingTo            *string    `json:"rating_to" parquet:"rating_to"`
+	RatingFromCanonical *int       `json:"rating_from_canonical" parquet:"rating_from_canonical"`
+	RatingToCanonical   *int       `json:"rating_to_canonical" parquet:"rating_to_canonical"`
+	TargetFrom          *string    `json:"target_from" parquet:"target_from"`
+	TargetTo            *string    `json:"target_to" parquet:"target_to"`
+	TargetFromValue     *float64   `json:"target_from_value" parquet:"target_from_value"`
+	TargetToValue       *float64   `json:"target_to_value" parquet:"target_to_value"`
+	TargetCurrency      *string    `json:"target_currency" parquet:"target_currency"`
+	TargetParseStatus   *string    `json:"target_parse_status" parquet:"target_parse_status"`
+	AnalysisDate        *time.Time `json:"analysis_date" parquet:"analysis_date"`
+	TotalScore          *float64   `json:"total_score" parquet:"total_score"`
+	RatingScore         *float64   `json:"rating_score" parquet:"rating_score"`
+	RatingChangeScore   *float64   `json:"rating_change_score" parquet:"rating_change_score"`
+	TargetChangeScore   *float64   `json:"target_change_score" parquet:"target_change_score"`
+	ActionScore         *float64   `json:"action_score" parquet:"action_score"`
+	CoverageScore       *float64   `json:"coverage_score" parquet:"coverage_score"`
+	Confidence          *string    `json:"confidence" parquet:"confidence"`
+	Reason              *string    `json:"reason" parquet:"reason"`
+	ScoreCalculatedAt   *time.Time `json:"score_calculated_at" parquet:"score_calculated_at"`
+	ScoreProfileVersion *string    `json:"score_profile_version" parquet:"score_profile_version"`
 }
 
 type FilterOption struct {
@@ -130,6 +454,9 @@ type MarketIntelligenceOverview struct {
 	TopActionTypes             []ActionTypeAnalytics  `json:"top_action_types"`
 	RecentActivityTrend        []ActivityTrendPoint   `json:"recent_activity_trend"`
 	AverageRecommendationScore float64                `json:"average_recommendation_score"`
+	TargetsRaised              int                    `json:"targets_raised"`
+	TargetsLowered             int                    `json:"targets_lowered"`
+	AverageTargetChange        float64                `json:"average_target_change_pct"`
 }
 
 type BrokerageAnalytics struct {
@@ -0,0 +1,208 @@
+// Package pricing parses the free-form price targets analysts publish, such
+// as "$1,234.50", "-$5.00" or "EUR 12.30", into numeric values.
+package pricing
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Parse statuses stored in stock_analysis.target_parse_status.
+const (
+	// StatusOK means every non-empty target parsed.
+	StatusOK = "ok"
+	// StatusEmpty means the analysis carries no price target.
+	StatusEmpty = "empty"
+	// StatusInvalid means a target was present but could not be parsed, or
+	// the two targets use different currencies.
+	StatusInvalid = "invalid"
+	// StatusPending marks rows written before targets were parsed; the
+	// backfill replaces it.
+	StatusPending = "pending"
+)
+
+// DefaultCurrency is assumed for bare numbers and a plain "$".
+const DefaultCurrency = "USD"
+
+// maxValue keeps parsed prices within the DECIMAL(18,4) target columns.
+const maxValue = 1e13
+
+// currencySymbols maps prefixes and suffixes to ISO 4217 codes. Longer
+// symbols come first so "US$" is not read as "$".
+var currencySymbols = []struct {
+	symbol   string
+	currency string
+}{
+	{"US$", "USD"},
+	{"C$", "CAD"},
+	{"CA$", "CAD"},
+	{"A$", "AUD"},
+	{"AU$", "AUD"},
+	{"HK$", "HKD"},
+	{"NZ$", "NZD"},
+	{"R$", "BRL"},
+	{"$", "USD"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"¥", "JPY"},
+	{"₹", "INR"},
+	{"CHF", "CHF"},
+	{"GBp", "GBX"},
+	{"GBX", "GBX"},
+}
+
+// Price is a parsed price target.
+type Price struct {
+	Value    float64
+	Currency string
+}
+
+// ParsePrice parses a single price. It accepts currency symbols or ISO codes
+// before or after the number, thousands separators, a leading minus sign and
+// accounting-style parentheses. ok is false for empty or unparseable input.
+func ParsePrice(raw string) (price Price, ok bool) {
+	text := strings.TrimSpace(raw)
+	if text == "" {
+		return Price{}, false
+	}
+
+	negative := false
+	if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+		negative = true
+		text = strings.TrimSpace(text[1 : len(text)-1])
+	}
+	if strings.HasPrefix(text, "-") {
+		negative = !negative
+		text = strings.TrimSpace(text[1:])
+	}
+
+	currency := ""
+	text, currency = stripCurrency(text)
+
+	// A minus sign may also follow the symbol, as in "$-5.00"
+	if strings.HasPrefix(text, "-") {
+		negative = !negative
+		text = strings.TrimSpace(text[1:])
+	}
+
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	number := strings.ReplaceAll(text, ",", "")
+	if !isPlainNumber(number) {
+		return Price{}, false
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value >= maxValue {
+		return Price{}, false
+	}
+	if negative {
+		value = -value
+	}
+
+	return Price{Value: value, Currency: currency}, true
+}
+
+// isPlainNumber rejects what ParseFloat would otherwise accept, such as
+// exponents, "NaN" and "Inf".
+func isPlainNumber(value string) bool {
+	digits := 0
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r != '.':
+			return false
+		}
+	}
+	return digits > 0
+}
+
+// stripCurrency removes a currency symbol or ISO code from either end of
+// text and returns what is left together with the currency code.
+func stripCurrency(text string) (string, string) {
+	for _, candidate := range currencySymbols {
+		if strings.HasPrefix(text, candidate.symbol) {
+			return strings.TrimSpace(text[len(candidate.symbol):]), candidate.currency
+		}
+		if strings.HasSuffix(text, candidate.symbol) {
+			return strings.TrimSpace(text[:len(text)-len(candidate.symbol)]), candidate.currency
+		}
+	}
+
+	// Three-letter ISO codes, e.g. "USD 12.00" or "12.00 EUR"
+	if fields := strings.Fields(text); len(fields) == 2 {
+		if isCurrencyCode(fields[0]) {
+			return fields[1], fields[0]
+		}
+		if isCurrencyCode(fields[1]) {
+			return fields[0], fields[1]
+		}
+	}
+
+	return text, ""
+}
+
+func isCurrencyCode(value string) bool {
+	if len(value) != 3 {
+		return false
+	}
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Targets is the parsed form of an analysis' target_from and target_to.
+type Targets struct {
+	From     *float64
+	To       *float64
+	Currency string
+	Status   string
+}
+
+// ParseTargets parses both targets of an analysis. A target that is present
+// but unparseable, or a currency mismatch between the two, makes the status
+// invalid; whatever did parse is still returned.
+func ParseTargets(from, to string) Targets {
+	targets := Targets{Status: StatusEmpty}
+	invalid := false
+
+	for _, side := range []struct {
+		raw   string
+		value **float64
+	}{{from, &targets.From}, {to, &targets.To}} {
+		if strings.TrimSpace(side.raw) == "" {
+			continue
+		}
+
+		price, ok := ParsePrice(side.raw)
+		if !ok {
+			invalid = true
+			continue
+		}
+
+		if targets.Currency != "" && targets.Currency != price.Currency {
+			invalid = true
+		}
+		if targets.Currency == "" {
+			targets.Currency = price.Currency
+		}
+
+		value := price.Value
+		*side.value = &value
+	}
+
+	switch {
+	case invalid:
+		targets.Status = StatusInvalid
+	case targets.From != nil || targets.To != nil:
+		targets.Status = StatusOK
+	}
+
+	return targets
+}
@@ -0,0 +1,74 @@
+package pricing
+
+import "testing"
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		raw      string
+		ok       bool
+		value    float64
+		currency string
+	}{
+		{"$1,234.50", true, 1234.5, "USD"},
+		{"-$5.00", true, -5, "USD"},
+		{"$-5", true, -5, "USD"},
+		{"(12.00)", true, -12, "USD"},
+		{"($12.00)", true, -12, "USD"},
+		{"EUR 12.30", true, 12.3, "EUR"},
+		{"12.30 EUR", true, 12.3, "EUR"},
+		{"12 CA$", true, 12, "CAD"},
+		{"C$12", true, 12, "CAD"},
+		{"12 AU$", true, 12, "AUD"},
+		{"US$3", true, 3, "USD"},
+		{"€7.5", true, 7.5, "EUR"},
+		{"42", true, 42, "USD"},
+		{"1e5", false, 0, ""},
+		{"NaN", false, 0, ""},
+		{"Inf", false, 0, ""},
+		{"$", false, 0, ""},
+		{"", false, 0, ""},
+		{"  ", false, 0, ""},
+		{"twelve", false, 0, ""},
+		{"$1.2.3", false, 0, ""},
+		{"99999999999999", false, 0, ""},
+	}
+
+	for _, tt := range tests {
+		price, ok := ParsePrice(tt.raw)
+		if ok != tt.ok {
+			t.Errorf("ParsePrice(%q) ok = %v, want %v", tt.raw, ok, tt.ok)
+			continue
+		}
+		if ok && (price.Value != tt.value || price.Currency != tt.currency) {
+			t.Errorf("ParsePrice(%q) = %v %s, want %v %s", tt.raw, price.Value, price.Currency, tt.value, tt.currency)
+		}
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		from, to string
+		status   string
+		currency string
+		hasFrom  bool
+		hasTo    bool
+	}{
+		{"$10.00", "$12.00", StatusOK, "USD", true, true},
+		{"", "$12.00", StatusOK, "USD", false, true},
+		{"", "", StatusEmpty, "", false, false},
+		{"$10.00", "EUR 12.00", StatusInvalid, "USD", true, true},
+		{"£4", "12 CA$", StatusInvalid, "GBP", true, true},
+		{"$10.00", "1e5", StatusInvalid, "USD", true, false},
+		{"NaN", "", StatusInvalid, "", false, false},
+	}
+
+	for _, tt := range tests {
+		targets := ParseTargets(tt.from, tt.to)
+		if targets.Status != tt.status || targets.Currency != tt.currency {
+			t.Errorf("ParseTargets(%q, %q) = %s %q, want %s %q", tt.from, tt.to, targets.Status, targets.Currency, tt.status, tt.currency)
+		}
+		if (targets.From != nil) != tt.hasFrom || (targets.To != nil) != tt.hasTo {
+			t.Errorf("ParseTargets(%q, %q) parsed from=%v to=%v, want from=%v to=%v", tt.from, tt.to, targets.From != nil, targets.To != nil, tt.hasFrom, tt.hasTo)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Package ratings defines the canonical 1-5 analyst rating scale that
+// brokerage-specific rating vocabularies are mapped onto.
+package ratings
+
+import "strings"
+
+const (
+	StrongSell = 1
+	Sell       = 2
+	Hold       = 3
+	Buy        = 4
+	StrongBuy  = 5
+)
+
+// Scale lists the canonical values with their labels, lowest first.
+var Scale = []ScalePoint{
+	{StrongSell, "Strong Sell"},
+	{Sell, "Sell"},
+	{Hold, "Hold"},
+	{Buy, "Buy"},
+	{StrongBuy, "Strong Buy"},
+}
+
+type ScalePoint struct {
+	Value int    `json:"value"`
+	Label string `json:"label"`
+}
+
+// Valid reports whether value is on the canonical scale.
+func Valid(value int) bool {
+	return value >= StrongSell && value <= StrongBuy
+}
+
+// Label returns the label of a canonical value, or "" if it is off the scale.
+func Label(value int) string {
+	if !Valid(value) {
+		return ""
+	}
+	return Scale[value-1].Label
+}
+
+// Normalize turns a raw rating into the key used in rating_mappings:
+// lower case, trimmed, with inner whitespace collapsed.
+func Normalize(raw string) string {
+	return strings.Join(strings.Fields(strings.ToLower(raw)), " ")
+}
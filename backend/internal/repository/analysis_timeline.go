@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"stock-api/internal/models"
+)
+
+// GetAnalysisTimeline returns up to params.Limit analyses of a stock that
+// match params, newest first, starting after params.After. With
+// IncludeArchived it also reads stock_analysis_archive, skipping archived
+// analyses that were ingested again since.
+func (r *StockRepository) GetAnalysisTimeline(stockID int, params models.AnalysisTimelineParams) ([]models.StockAnalysis, error) {
+	queryArgs := []any{stockID}
+	bind := func(value any) string {
+		queryArgs = append(queryArgs, value)
+		return fmt.Sprintf("$%d", len(queryArgs))
+	}
+
+	source := `
+		SELECT id, stock_id, target_from, target_to, target_from_value, target_to_value, target_currency,
+			target_parse_status, action, action_type, brokerage, rating_from, rating_to,
+			rating_from_canonical, rating_to_canonical, analysis_date, created_at, false AS archived
+		FROM stock_analysis
+		WHERE stock_id = $1`
+	if params.IncludeArchived {
+		source += `
+		UNION ALL
+		SELECT a.id, a.stock_id, a.target_from, a.target_to, a.target_from_value, a.target_to_value, a.target_currency,
+			a.target_parse_status, a.action, a.action_type, a.brokerage, a.rating_from, a.rating_to,
+			a.rating_from_canonical, a.rating_to_canonical, a.analysis_date, COALESCE(a.created_at, a.archived_at), true
+		FROM stock_analysis_archive a
+		WHERE a.stock_id = $1
+		AND NOT EXISTS (
+			SELECT 1 FROM stock_analysis l
+			WHERE l.stock_id = a.stock_id AND l.analysis_date = a.analysis_date AND l.brokerage = a.brokerage
+		)`
+	}
+
+	conditions := []string{}
+	if params.From != nil {
+		conditions = append(conditions, "sa.analysis_date >= "+bind(*params.From))
+	}
+	if params.To != nil {
+		conditions = append(conditions, "sa.analysis_date < "+bind(*params.To))
+	}
+	if params.Brokerage != "" {
+		conditions = append(conditions, "LOWER(sa.brokerage) LIKE LOWER("+bind("%"+params.Brokerage+"%")+")")
+	}
+	if params.ActionType != "" {
+		conditions = append(conditions, "sa.action_type = "+bind(params.ActionType))
+	}
+	if params.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(sa.analysis_date, sa.id) < (%s, %s)",
+			bind(params.After.AnalysisDate), bind(params.After.ID)))
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := `
+		SELECT id, stock_id, COALESCE(target_from, ''), COALESCE(target_to, ''), target_from_value, target_to_value,
+			COALESCE(target_currency, ''), COALESCE(target_parse_status, ''),
+			COALESCE(action, ''), COALESCE(action_type, ''), COALESCE(brokerage, ''),
+			COALESCE(rating_from, ''), COALESCE(rating_to, ''), rating_from_canonical, rating_to_canonical,
+			analysis_date, created_at, archived
+		FROM (` + source + `
+		) sa
+		` + whereClause + `
+		ORDER BY sa.analysis_date DESC, sa.id DESC
+		LIMIT ` + bind(params.Limit)
+
+	rows, err := r.db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analysis timeline: %w", err)
+	}
+	defer rows.Close()
+
+	analyses := []models.StockAnalysis{}
+	for rows.Next() {
+		var analysis models.StockAnalysis
+		var targetFromValue, targetToValue sql.NullFloat64
+		var ratingFromCanonical, ratingToCanonical sql.NullInt64
+		err := rows.Scan(
+			&analysis.ID, &analysis.StockID, &analysis.TargetFrom, &analysis.TargetTo,
+			&targetFromValue, &targetToValue, &analysis.TargetCurrency, &analysis.TargetParseStatus,
+			&analysis.Action, &analysis.ActionType, &analysis.Brokerage, &analysis.RatingFrom, &analysis.RatingTo,
+			&ratingFromCanonical, &ratingToCanonical, &analysis.AnalysisDate, &analysis.CreatedAt, &analysis.Archived,
+		)
+		if err != nil {
+			return nil, err
+		}
+		analysis.TargetFromValue = nullFloatPtr(targetFromValue)
+		analysis.TargetToValue = nullFloatPtr(targetToValue)
+		setCanonicalRatings(&analysis, ratingFromCanonical, ratingToCanonical)
+		analyses = append(analyses, analysis)
+	}
+
+	return analyses, rows.Err()
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"stock-api/internal/models"
+)
+
+const ProcessBrokerageStats = "brokerage_stats"
+
+// priceLookupSlackDays is how far from the call date, and from the end of
+// the horizon, a closing price may be to still stand for it. It bridges
+// weekends and holidays without evaluating calls against unrelated prices.
+const priceLookupSlackDays = 7
+
+type BrokerageStatsRepository struct {
+	db *sql.DB
+}
+
+func NewBrokerageStatsRepository(db *sql.DB) *BrokerageStatsRepository {
+	return &BrokerageStatsRepository{db: db}
+}
+
+// RefreshBrokerageStats recomputes the track record of every brokerage from
+// live and archived analyses, evaluating rating changes against the closing
+// price horizonDays later. Brokerages without analyses left are removed. It
+// returns the number of brokerages stored.
+func (r *BrokerageStatsRepository) RefreshBrokerageStats(horizonDays int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin brokerage stats transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	computedAt := time.Now().UTC()
+
+	// Archived rows with a live duplicate (re-ingested after archiving) are
+	// counted once
+	query := `
+		WITH analyses AS (
+			SELECT stock_id, brokerage, analysis_date, rating_from_canonical, rating_to_canonical
+			FROM stock_analysis
+			UNION ALL
+			SELECT a.stock_id, a.brokerage, a.analysis_date, a.rating_from_canonical, a.rating_to_canonical
+			FROM stock_analysis_archive a
+			WHERE NOT EXISTS (
+				SELECT 1 FROM stock_analysis sa
+				WHERE sa.stock_id = a.stock_id AND sa.analysis_date = a.analysis_date AND sa.brokerage = a.brokerage
+			)
+		),
+		calls AS (
+			SELECT
+				a.brokerage,
+				SIGN(a.rating_to_canonical - a.rating_from_canonical) AS direction,
+				(
+					SELECT p.close_price FROM stock_prices p
+					WHERE p.stock_id = a.stock_id
+						AND p.price_date <= a.analysis_date::DATE
+						AND p.price_date > a.analysis_date::DATE - $3::INT
+					ORDER BY p.price_date DESC
+					LIMIT 1
+				) AS price_at_call,
+				(
+					SELECT p.close_price FROM stock_prices p
+					WHERE p.stock_id = a.stock_id
+						AND p.price_date >= (a.analysis_date + $1::INT * INTERVAL '1 day')::DATE
+						AND p.price_date < (a.analysis_date + $1::INT * INTERVAL '1 day')::DATE + $3::INT
+					ORDER BY p.price_date
+					LIMIT 1
+				) AS price_after
+			FROM analyses a
+			WHERE a.brokerage <> ''
+				AND a.rating_to_canonical IS NOT NULL AND a.rating_from_canonical IS NOT NULL
+				AND a.rating_to_canonical <> a.rating_from_canonical
+		),
+		evaluated AS (
+			SELECT
+				brokerage,
+				COUNT(*) AS evaluated_calls,
+				COUNT(*) FILTER (WHERE SIGN(price_after - price_at_call) = direction) AS hits
+			FROM calls
+			WHERE price_at_call IS NOT NULL AND price_after IS NOT NULL
+			GROUP BY brokerage
+		)
+		INSERT INTO brokerage_stats (
+			brokerage, analyses_count, stocks_covered, upgrades, downgrades,
+			evaluated_calls, hits, horizon_days, first_analysis_at, last_analysis_at, computed_at
+		)
+		SELECT
+			a.brokerage,
+			COUNT(*),
+			COUNT(DISTINCT a.stock_id),
+			COUNT(*) FILTER (WHERE a.rating_to_canonical > a.rating_from_canonical),
+			COUNT(*) FILTER (WHERE a.rating_to_canonical < a.rating_from_canonical),
+			COALESCE(MAX(e.evaluated_calls), 0),
+			COALESCE(MAX(e.hits), 0),
+			$1,
+			MIN(a.analysis_date),
+			MAX(a.analysis_date),
+			$2
+		FROM analyses a
+		LEFT JOIN evaluated e ON e.brokerage = a.brokerage
+		WHERE a.brokerage <> ''
+		GROUP BY a.brokerage
+		ON CONFLICT (brokerage) DO UPDATE SET
+			analyses_count = EXCLUDED.analyses_count,
+			stocks_covered = EXCLUDED.stocks_covered,
+			upgrades = EXCLUDED.upgrades,
+			downgrades = EXCLUDED.downgrades,
+			evaluated_calls = EXCLUDED.evaluated_calls,
+			hits = EXCLUDED.hits,
+			horizon_days = EXCLUDED.horizon_days,
+			first_analysis_at = EXCLUDED.first_analysis_at,
+			last_analysis_at = EXCLUDED.last_analysis_at,
+			computed_at = EXCLUDED.computed_at`
+
+	result, err := tx.Exec(query, horizonDays, computedAt, priceLookupSlackDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute brokerage stats: %w", err)
+	}
+	stored, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM brokerage_stats WHERE computed_at < $1`, computedAt); err != nil {
+		return 0, fmt.Errorf("failed to remove stale brokerage stats: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit brokerage stats: %w", err)
+	}
+	return int(stored), nil
+}
+
+// GetBrokerageStatsComputedAt returns when the stored stats were last
+// refreshed, or the zero time when there are none. Every refresh stamps all
+// rows alike, so it changes with each refresh.
+func (r *BrokerageStatsRepository) GetBrokerageStatsComputedAt() (time.Time, error) {
+	var computedAt sql.NullTime
+	if err := r.db.QueryRow(`SELECT MAX(computed_at) FROM brokerage_stats`).Scan(&computedAt); err != nil {
+		return time.Time{}, err
+	}
+	return computedAt.Time, nil
+}
+
+// GetBrokerageStats returns the stored track record of every brokerage, the
+// most active first. Weights are left for the caller to derive.
+func (r *BrokerageStatsRepository) GetBrokerageStats() ([]models.BrokerageStats, error) {
+	query := `
+		SELECT brokerage, analyses_count, stocks_covered, upgrades, downgrades,
+			evaluated_calls, hits, horizon_days, first_analysis_at, last_analysis_at, computed_at
+		FROM brokerage_stats
+		ORDER BY analyses_count DESC, brokerage`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []models.BrokerageStats{}
+	for rows.Next() {
+		var stat models.BrokerageStats
+		var firstAnalysisAt, lastAnalysisAt sql.NullTime
+		err := rows.Scan(
+			&stat.Brokerage, &stat.AnalysesCount, &stat.StocksCovered, &stat.Upgrades, &stat.Downgrades,
+			&stat.EvaluatedCalls, &stat.Hits, &stat.HorizonDays, &firstAnalysisAt, &lastAnalysisAt, &stat.ComputedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan brokerage stats: %w", err)
+		}
+
+		stat.FirstAnalysisAt = nullTimePtr(firstAnalysisAt)
+		stat.LastAnalysisAt = nullTimePtr(lastAnalysisAt)
+		if stat.EvaluatedCalls > 0 {
+			hitRate := float64(stat.Hits) / float64(stat.EvaluatedCalls)
+			stat.HitRate = &hitRate
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+
+	"stock-api/internal/models"
+)
+
+// exportFetchSize is how many rows each FETCH pulls from the export cursor.
+const exportFetchSize = 1000
+
+// StreamStockExport walks every analysis of the stocks matching filters,
+// joined with their recommendation score under strategy, through a server-side cursor, so
+// only exportFetchSize rows are held in memory at a time. Stocks are ordered
+// like GET /stocks and their analyses newest first. Returning an error from
+// fn stops the export.
+func (r *StockRepository) StreamStockExport(ctx context.Context, filters models.StockFilterParams, strategy string, fn func(models.ExportRow) error) error {
+	// DECLARE cannot take placeholders, so filter values are inlined
+	whereClause := stockFilterWhereClause(filters, func(value any) string {
+		if number, ok := value.(float64); ok {
+			return strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		return pq.QuoteLiteral(fmt.Sprint(value))
+	})
+
+	orderBy := "s.symbol ASC"
+	switch filters.SortBy {
+	case "newest":
+		orderBy = "s.updated_at DESC"
+	case "oldest":
+		orderBy = "s.updated_at ASC"
+	case "company-a-z":
+		orderBy = "s.name ASC"
+	case "analysis-newest":
+		orderBy = "(SELECT MAX(analysis_date) FROM stock_analysis WHERE stock_id = s.id) DESC NULLS LAST, s.symbol ASC"
+	case "analysis-oldest":
+		orderBy = "(SELECT MAX(analysis_date) FROM stock_analysis WHERE stock_id = s.id) ASC NULLS LAST, s.symbol ASC"
+	}
+
+	query := `
+		DECLARE stock_export CURSOR FOR
+		SELECT
+			s.id, s.symbol, s.name,
+			sa.id, sa.brokerage, sa.action, sa.action_type, sa.rating_from, sa.rating_to,
+			sa.rating_from_canonical, sa.rating_to_canonical, sa.target_from, sa.target_to, sa.target_from_value, sa.target_to_value,
+			sa.target_currency, sa.target_parse_status, sa.analysis_date,
+			rs.total_score, rs.rating_score, rs.rating_change_score, rs.target_change_score,
+			rs.action_score, rs.coverage_score, rs.confidence, rs.reason, rs.calculated_at, rs.profile_version
+		FROM stocks s
+		LEFT JOIN stock_analysis sa ON sa.stock_id = s.id
+		LEFT JOIN recommendation_scores rs ON rs.stock_id = s.id AND rs.strategy = ` + pq.QuoteLiteral(strategy) + `
+		` + whereClause + `
+		ORDER BY ` + orderBy + `, s.id, sa.analysis_date DESC, sa.id DESC`
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to open export cursor: %w", err)
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM stock_export", exportFetchSize)
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch export rows: %w", err)
+		}
+
+		fetched := 0
+		for rows.Next() {
+			fetched++
+
+			row, err := scanExportRow(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if err := fn(row); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read export rows: %w", err)
+		}
+		rows.Close()
+
+		if fetched < exportFetchSize {
+			break
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "CLOSE stock_export"); err != nil {
+		return fmt.Errorf("failed to close export cursor: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func scanExportRow(rows *sql.Rows) (models.ExportRow, error) {
+	var row models.ExportRow
+	var analysisID, ratingFromCanonical, ratingToCanonical sql.NullInt64
+	var brokerage, action, ratingFrom, ratingTo, targetFrom, targetTo, confidence, reason, profileVersion sql.NullString
+	var actionType, targetCurrency, targetParseStatus sql.NullString
+	var targetFromValue, targetToValue sql.NullFloat64
+	var analysisDate, calculatedAt sql.NullTime
+	var totalScore, ratingScore, ratingChangeScore, targetChangeScore, actionScore, coverageScore sql.NullFloat64
+
+	err := rows.Scan(
+		&row.StockID, &row.Symbol, &row.Company,
+		&analysisID, &brokerage, &action, &actionType, &ratingFrom, &ratingTo,
+		&ratingFromCanonical, &ratingToCanonical, &targetFrom, &targetTo, &targetFromValue, &targetToValue,
+		&targetCurrency, &targetParseStatus, &analysisDate,
+		&totalScore, &ratingScore, &ratingChangeScore, &targetChangeScore,
+		&actionScore, &coverageScore, &confidence, &reason, &calculatedAt, &profileVersion,
+	)
+	if err != nil {
+		return row, fmt.Errorf("failed to scan export row: %w", err)
+	}
+
+	if analysisID.Valid {
+		id := int(analysisID.Int64)
+		row.AnalysisID = &id
+	}
+	row.Brokerage = nullStringPtr(brokerage)
+	row.Action = nullStringPtr(action)
+	row.ActionType = nullStringPtr(actionType)
+	row.RatingFrom = nullStringPtr(ratingFrom)
+	row.RatingTo = nullStringPtr(ratingTo)
+	row.RatingFromCanonical = nullIntPtr(ratingFromCanonical)
+	row.RatingToCanonical = nullIntPtr(ratingToCanonical)
+	row.TargetFrom = nullStringPtr(targetFrom)
+	row.TargetTo = nullStringPtr(targetTo)
+	row.TargetFromValue = nullFloatPtr(targetFromValue)
+	row.TargetToValue = nullFloatPtr(targetToValue)
+	row.TargetCurrency = nullStringPtr(targetCurrency)
+	row.TargetParseStatus = nullStringPtr(targetParseStatus)
+	row.AnalysisDate = nullTimePtr(analysisDate)
+	row.TotalScore = nullFloatPtr(totalScore)
+	row.RatingScore = nullFloatPtr(ratingScore)
+	row.RatingChangeScore = nullFloatPtr(ratingChangeScore)
+	row.TargetChangeScore = nullFloatPtr(targetChangeScore)
+	row.ActionScore = nullFloatPtr(actionScore)
+	row.CoverageScore = nullFloatPtr(coverageScore)
+	row.Confidence = nullStringPtr(confidence)
+	row.Reason = nullStringPtr(reason)
+	row.ScoreCalculatedAt = nullTimePtr(calculatedAt)
+	row.ScoreProfileVersion = nullStringPtr(profileVersion)
+
+	return row, nil
+}
+
+func nullStringPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	number := int(value.Int64)
+	return &number
+}
+
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+func nullFloatPtr(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
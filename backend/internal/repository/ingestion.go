@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"stock-api/internal/actions"
+	"stock-api/internal/models"
+	"stock-api/internal/pricing"
+)
+
+// ingestBatchSize bounds the rows per multi-row INSERT so large batches stay
+// well under the driver's parameter limit.
+const ingestBatchSize = 500
+
+// ingestMaxAttempts bounds how often a batch is retried after losing a
+// serialization conflict to a concurrent writer.
+const ingestMaxAttempts = 5
+
+type analysisKey struct {
+	stockID   int
+	date      string
+	brokerage string
+}
+
+func newAnalysisKey(stockID int, date time.Time, brokerage string) analysisKey {
+	return analysisKey{stockID: stockID, date: storedAnalysisDate(date).Format(time.RFC3339Nano), brokerage: brokerage}
+}
+
+// storedAnalysisDate is date as analysis_date stores it. The column keeps
+// microseconds, so records that differ only below that share a row, and keys
+// built from incoming records must match keys read back from the database.
+func storedAnalysisDate(date time.Time) time.Time {
+	return date.UTC().Truncate(time.Microsecond)
+}
+
+// IngestAnalysisBatch writes a batch of analyst records in one transaction
+// using multi-row upserts on stocks.symbol and idx_stock_analysis_unique, then
+// archives the analyses of each touched stock that retention no longer keeps. Either the whole
+// batch is stored or none of it is. Records without a ticker or date are
+// counted as failed and skipped. Batches written concurrently may touch the
+// same stocks, so a transaction aborted by a serialization conflict is
+// retried from scratch.
+func (r *StockRepository) IngestAnalysisBatch(records []models.AnalysisRecord, retention RetentionPolicy) (*models.IngestResult, error) {
+	failed := 0
+	valid := make([]models.AnalysisRecord, 0, len(records))
+	for _, record := range records {
+		if strings.TrimSpace(record.Symbol) == "" || record.AnalysisDate.IsZero() {
+			failed++
+			continue
+		}
+		valid = append(valid, record)
+	}
+	if len(valid) == 0 {
+		return &models.IngestResult{AnalysesFailed: failed}, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := r.ingestValidRecords(valid, retention)
+		if err == nil {
+			result.AnalysesFailed = failed
+			return result, nil
+		}
+		if attempt >= ingestMaxAttempts || !isRetryableTxError(err) {
+			return nil, err
+		}
+		time.Sleep(time.Duration(attempt*attempt) * 20 * time.Millisecond)
+	}
+}
+
+func (r *StockRepository) ingestValidRecords(records []models.AnalysisRecord, retention RetentionPolicy) (*models.IngestResult, error) {
+	result := &models.IngestResult{}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin ingestion transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stockIDs, err := upsertStocks(tx, records, result)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver, err := loadRatingResolver(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	writtenStocks, err := upsertAnalyses(tx, records, stockIDs, resolver, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retention covers every stock in the batch, so a tightened policy also
+	// reaches stocks without new analyses
+	batchStockIDs := make([]int, 0, len(stockIDs))
+	for _, id := range stockIDs {
+		batchStockIDs = append(batchStockIDs, id)
+	}
+	sort.Ints(batchStockIDs)
+
+	archived, archivedFrom, err := archiveAnalyses(tx, batchStockIDs, retention)
+	if err != nil {
+		return nil, err
+	}
+	result.AnalysesArchived = archived
+
+	// Only stocks whose analyses changed need rescoring
+	for _, id := range archivedFrom {
+		writtenStocks[id] = true
+	}
+	for _, id := range batchStockIDs {
+		if writtenStocks[id] {
+			result.StockIDs = append(result.StockIDs, id)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit ingestion transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// isRetryableTxError reports whether the database aborted a transaction
+// because of a conflict with another one (serialization failure or deadlock).
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// upsertStocks creates or renames every distinct symbol in records and
+// returns the stock ID of each one.
+func upsertStocks(tx *sql.Tx, records []models.AnalysisRecord, result *models.IngestResult) (map[string]int, error) {
+	names := make(map[string]string)
+	symbols := []string{}
+	for _, record := range records {
+		if _, seen := names[record.Symbol]; !seen {
+			symbols = append(symbols, record.Symbol)
+		}
+		names[record.Symbol] = record.Company
+	}
+	// A stable order keeps concurrent batches locking rows in the same order
+	sort.Strings(symbols)
+
+	existing := make(map[string]bool)
+	rows, err := tx.Query(`SELECT symbol FROM stocks WHERE symbol = ANY($1)`, pq.Array(symbols))
+	if err != nil {
+		return nil, fmt.Errorf("error checking existing stocks: %w", err)
+	}
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[symbol] = true
+	}
+	rows.Close()
+
+	stockIDs := make(map[string]int, len(symbols))
+	for start := 0; start < len(symbols); start += ingestBatchSize {
+		end := start + ingestBatchSize
+		if end > len(symbols) {
+			end = len(symbols)
+		}
+
+		args := []any{}
+		for _, symbol := range symbols[start:end] {
+			args = append(args, symbol, names[symbol])
+		}
+
+		query := `
+			INSERT INTO stocks (symbol, name)
+			VALUES ` + valuesPlaceholders(end-start, 2) + `
+			ON CONFLICT (symbol) DO UPDATE SET name = EXCLUDED.name, updated_at = NOW()
+			RETURNING id, symbol`
+
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("error upserting stocks: %w", err)
+		}
+		for rows.Next() {
+			var id int
+			var symbol string
+			if err := rows.Scan(&id, &symbol); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			stockIDs[symbol] = id
+		}
+		rows.Close()
+	}
+
+	for _, symbol := range symbols {
+		if existing[symbol] {
+			result.StocksUpdated++
+		} else {
+			result.StocksCreated++
+		}
+	}
+
+	return stockIDs, nil
+}
+
+// upsertAnalyses inserts new analyses and updates existing ones only when a
+// field changed, so unchanged rows are neither rewritten nor returned. Raw
+// ratings are resolved to canonical ones through resolver on the way in.
+// Analyses already in the archive are skipped as unchanged, since retention
+// would only archive them again. It returns the stocks whose analyses were
+// written.
+func upsertAnalyses(tx *sql.Tx, records []models.AnalysisRecord, stockIDs map[string]int, resolver *ratingResolver, result *models.IngestResult) (map[int]bool, error) {
+	// ON CONFLICT cannot touch the same row twice in one statement, so the
+	// last record for each key wins
+	latest := make(map[analysisKey]models.AnalysisRecord)
+	keys := []analysisKey{}
+	ids := []int{}
+	for _, record := range records {
+		key := newAnalysisKey(stockIDs[record.Symbol], record.AnalysisDate, record.Brokerage)
+		if _, seen := latest[key]; seen {
+			result.AnalysesUnchanged++
+		} else {
+			keys = append(keys, key)
+			ids = append(ids, key.stockID)
+		}
+		latest[key] = record
+	}
+
+	existing, err := loadAnalysisKeys(tx, "stock_analysis", ids)
+	if err != nil {
+		return nil, fmt.Errorf("error checking existing analyses: %w", err)
+	}
+	archived, err := loadAnalysisKeys(tx, "stock_analysis_archive", ids)
+	if err != nil {
+		return nil, fmt.Errorf("error checking archived analyses: %w", err)
+	}
+
+	current := keys[:0]
+	for _, key := range keys {
+		if archived[key] && !existing[key] {
+			result.AnalysesUnchanged++
+			continue
+		}
+		current = append(current, key)
+	}
+	keys = current
+
+	written := make(map[analysisKey]bool)
+	for start := 0; start < len(keys); start += ingestBatchSize {
+		end := start + ingestBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		args := []any{}
+		for _, key := range keys[start:end] {
+			record := latest[key]
+			targets := pricing.ParseTargets(record.TargetFrom, record.TargetTo)
+			args = append(args, key.stockID, record.TargetFrom, record.TargetTo,
+				targets.From, targets.To, nullIfEmpty(targets.Currency), targets.Status,
+				record.Action, actions.Classify(record.Action), record.Brokerage, record.RatingFrom, record.RatingTo,
+				resolver.resolve(record.Brokerage, record.RatingFrom), resolver.resolve(record.Brokerage, record.RatingTo),
+				storedAnalysisDate(record.AnalysisDate))
+		}
+
+		query := `
+			INSERT INTO stock_analysis (stock_id, target_from, target_to, target_from_value, target_to_value,
+				target_currency, target_parse_status, action, action_type, brokerage, rating_from, rating_to,
+				rating_from_canonical, rating_to_canonical, analysis_date)
+			VALUES ` + valuesPlaceholders(end-start, 15) + `
+			ON CONFLICT (stock_id, analysis_date, brokerage) DO UPDATE SET
+				target_from = EXCLUDED.target_from,
+				target_to = EXCLUDED.target_to,
+				target_from_value = EXCLUDED.target_from_value,
+				target_to_value = EXCLUDED.target_to_value,
+				target_currency = EXCLUDED.target_currency,
+				target_parse_status = EXCLUDED.target_parse_status,
+				action = EXCLUDED.action,
+				action_type = EXCLUDED.action_type,
+				rating_from = EXCLUDED.rating_from,
+				rating_to = EXCLUDED.rating_to,
+				rating_from_canonical = EXCLUDED.rating_from_canonical,
+				rating_to_canonical = EXCLUDED.rating_to_canonical
+			WHERE stock_analysis.target_from IS DISTINCT FROM EXCLUDED.target_from
+				OR stock_analysis.target_to IS DISTINCT FROM EXCLUDED.target_to
+				OR stock_analysis.action IS DISTINCT FROM EXCLUDED.action
+				OR stock_analysis.action_type IS DISTINCT FROM EXCLUDED.action_type
+				OR stock_analysis.rating_from IS DISTINCT FROM EXCLUDED.rating_from
+				OR stock_analysis.rating_to IS DISTINCT FROM EXCLUDED.rating_to
+				OR stock_analysis.rating_from_canonical IS DISTINCT FROM EXCLUDED.rating_from_canonical
+				OR stock_analysis.rating_to_canonical IS DISTINCT FROM EXCLUDED.rating_to_canonical
+			RETURNING stock_id, analysis_date, brokerage`
+
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("error upserting analyses: %w", err)
+		}
+		for rows.Next() {
+			var stockID int
+			var date time.Time
+			var brokerage sql.NullString
+			if err := rows.Scan(&stockID, &date, &brokerage); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			written[newAnalysisKey(stockID, date, brokerage.String)] = true
+		}
+		rows.Close()
+	}
+
+	writtenStocks := make(map[int]bool)
+	for key := range written {
+		if existing[key] {
+			result.AnalysesUpdated++
+		} else {
+			result.AnalysesInserted++
+		}
+		writtenStocks[key.stockID] = true
+	}
+	result.AnalysesUnchanged += len(keys) - len(written)
+
+	return writtenStocks, nil
+}
+
+// loadAnalysisKeys returns the keys of the analyses of stockIDs in table,
+// which is stock_analysis or stock_analysis_archive.
+func loadAnalysisKeys(tx *sql.Tx, table string, stockIDs []int) (map[analysisKey]bool, error) {
+	rows, err := tx.Query(`SELECT stock_id, analysis_date, brokerage FROM `+table+` WHERE stock_id = ANY($1)`, intArray(stockIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[analysisKey]bool)
+	for rows.Next() {
+		var stockID int
+		var date time.Time
+		var brokerage sql.NullString
+		if err := rows.Scan(&stockID, &date, &brokerage); err != nil {
+			return nil, err
+		}
+		keys[newAnalysisKey(stockID, date, brokerage.String)] = true
+	}
+	return keys, rows.Err()
+}
+
+// intArray converts IDs to a typed array parameter for "= ANY($n)" filters.
+func intArray(ids []int) interface{} {
+	values := make([]int64, len(ids))
+	for i, id := range ids {
+		values[i] = int64(id)
+	}
+	return pq.Array(values)
+}
+
+// nullIfEmpty stores empty strings as NULL.
+func nullIfEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// valuesPlaceholders returns "($1, $2), ($3, $4)" style placeholders for a
+// multi-row VALUES clause.
+func valuesPlaceholders(rowCount, columnCount int) string {
+	rows := make([]string, rowCount)
+	for i := range rows {
+		placeholders := make([]string, columnCount)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", i*columnCount+j+1)
+		}
+		rows[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+	return strings.Join(rows, ", ")
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalysisKeyMatchesStoredDate(t *testing.T) {
+	incoming := time.Date(2025, 7, 1, 0, 30, 5, 123456789, time.UTC)
+	// analysis_date keeps microseconds, so this is what reading it back yields
+	stored := time.Date(2025, 7, 1, 0, 30, 5, 123456000, time.UTC)
+
+	if newAnalysisKey(1, incoming, "Jefferies") != newAnalysisKey(1, stored, "Jefferies") {
+		t.Fatal("an incoming record does not match its stored row")
+	}
+
+	// Records in the same microsecond share a row and must collapse to one key
+	sameMicrosecond := time.Date(2025, 7, 1, 0, 30, 5, 123456999, time.UTC)
+	if newAnalysisKey(1, incoming, "Jefferies") != newAnalysisKey(1, sameMicrosecond, "Jefferies") {
+		t.Fatal("records in the same microsecond have different keys")
+	}
+
+	local := incoming.In(time.FixedZone("EST", -5*60*60))
+	if newAnalysisKey(1, incoming, "Jefferies") != newAnalysisKey(1, local, "Jefferies") {
+		t.Fatal("the key depends on the time zone")
+	}
+
+	if newAnalysisKey(1, incoming, "Jefferies") == newAnalysisKey(1, incoming.Add(time.Microsecond), "Jefferies") {
+		t.Fatal("records a microsecond apart share a key")
+	}
+}
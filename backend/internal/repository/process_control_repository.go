@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,7 +10,15 @@ import (
 )
 
 const (
-	ProcessStockSync = "stock_sync"
+	ProcessStockSync             = "stock_sync"
+	ProcessRecommendationRescore = "recommendation_rescore"
+)
+
+var (
+	// ErrProcessLocked is returned when another owner holds a live lease.
+	ErrProcessLocked = errors.New("process is already running")
+	// ErrLockLost is returned when the caller's lease expired or was released.
+	ErrLockLost = errors.New("process lock is no longer held by this owner")
 )
 
 type ProcessControlRepository struct {
@@ -20,92 +29,264 @@ func NewProcessControlRepository(db *sql.DB) *ProcessControlRepository {
 	return &ProcessControlRepository{db: db}
 }
 
-func (r *ProcessControlRepository) GetProcessControl(processName string) (*models.ProcessControl, error) {
-	query := `
-		SELECT id, process_name, is_running, last_execution, interval_minutes, created_at, updated_at
-		FROM process_control WHERE process_name = $1`
-	
+// A lock is stale when it is marked running but its lease has expired, or it
+// was taken before leases existed and has none.
+const processControlColumns = `
+	id, process_name, is_running, last_execution, interval_minutes,
+	COALESCE(checkpoint, ''), checkpoint_updated_at,
+	COALESCE(owner_id, ''), lease_expires_at, heartbeat_at,
+	(is_running AND (lease_expires_at IS NULL OR lease_expires_at < NOW())),
+	enabled, COALESCE(schedule, ''), created_at, updated_at`
+
+func scanProcessControl(row rowScanner) (*models.ProcessControl, error) {
 	process := &models.ProcessControl{}
-	err := r.db.QueryRow(query, processName).Scan(
+	err := row.Scan(
 		&process.ID, &process.ProcessName, &process.IsRunning, &process.LastExecution,
-		&process.IntervalMinutes, &process.CreatedAt, &process.UpdatedAt,
+		&process.IntervalMinutes, &process.Checkpoint, &process.CheckpointUpdatedAt,
+		&process.OwnerID, &process.LeaseExpiresAt, &process.HeartbeatAt, &process.LockStale,
+		&process.Enabled, &process.Schedule, &process.CreatedAt, &process.UpdatedAt,
 	)
-	
+	if err != nil {
+		return nil, err
+	}
+	return process, nil
+}
+
+func (r *ProcessControlRepository) GetProcessControl(processName string) (*models.ProcessControl, error) {
+	query := `SELECT ` + processControlColumns + ` FROM process_control WHERE process_name = $1`
+
+	process, err := scanProcessControl(r.db.QueryRow(query, processName))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	
+
 	return process, err
 }
 
+func (r *ProcessControlRepository) ListProcessControls() ([]models.ProcessControl, error) {
+	query := `SELECT ` + processControlColumns + ` FROM process_control ORDER BY process_name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	processes := []models.ProcessControl{}
+	for rows.Next() {
+		process, err := scanProcessControl(rows)
+		if err != nil {
+			return nil, err
+		}
+		processes = append(processes, *process)
+	}
+
+	return processes, rows.Err()
+}
+
 func (r *ProcessControlRepository) CanStartProcess(processName string) (bool, error) {
 	process, err := r.GetProcessControl(processName)
 	if err != nil {
 		return false, err
 	}
-	
+
 	if process == nil {
 		return false, fmt.Errorf("process %s not found in control table", processName)
 	}
-	
-	// If process is already running, cannot start
-	if process.IsRunning {
+
+	// If process is running under a live lease, cannot start
+	if process.IsRunning && !process.LockStale {
 		return false, nil
 	}
-	
+
 	// If no last execution, can start
 	if process.LastExecution == nil {
 		return true, nil
 	}
-	
+
 	// Check if enough time has passed since last execution
 	now := time.Now()
 	nextAllowedExecution := process.LastExecution.Add(time.Duration(process.IntervalMinutes) * time.Minute)
-	
+
 	return now.After(nextAllowedExecution), nil
 }
 
-func (r *ProcessControlRepository) StartProcess(processName string) error {
+// StartProcess takes the lock for ownerID with the given lease. A lock whose
+// lease has expired is reclaimed, so a crashed holder never blocks the
+// process forever.
+func (r *ProcessControlRepository) StartProcess(processName, ownerID string, lease time.Duration) error {
 	query := `
 		UPDATE process_control 
-		SET is_running = true, updated_at = NOW()
-		WHERE process_name = $1 AND is_running = false`
-	
-	result, err := r.db.Exec(query, processName)
+		SET is_running = true, owner_id = $2, lease_expires_at = NOW() + $3::INT * INTERVAL '1 second',
+			heartbeat_at = NOW(), updated_at = NOW()
+		WHERE process_name = $1
+		AND (is_running = false OR lease_expires_at IS NULL OR lease_expires_at < NOW())`
+
+	result, err := r.db.Exec(query, processName, ownerID, int(lease.Seconds()))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("process %s is already running or not found: %w", processName, ErrProcessLocked)
+	}
+
+	return nil
+}
+
+// HeartbeatProcess extends the lease held by ownerID. It returns ErrLockLost
+// if the lock has been reclaimed or force-released in the meantime.
+func (r *ProcessControlRepository) HeartbeatProcess(processName, ownerID string, lease time.Duration) error {
+	query := `
+		UPDATE process_control 
+		SET lease_expires_at = NOW() + $3::INT * INTERVAL '1 second', heartbeat_at = NOW(), updated_at = NOW()
+		WHERE process_name = $1 AND owner_id = $2 AND is_running = true`
+
+	result, err := r.db.Exec(query, processName, ownerID, int(lease.Seconds()))
 	if err != nil {
 		return err
 	}
-	
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	
+
 	if rowsAffected == 0 {
-		return fmt.Errorf("process %s is already running or not found", processName)
+		return fmt.Errorf("process %s: %w", processName, ErrLockLost)
 	}
-	
+
 	return nil
 }
 
-func (r *ProcessControlRepository) FinishProcess(processName string) error {
+// FinishProcess releases the lock if ownerID still holds it and records the
+// execution time.
+func (r *ProcessControlRepository) FinishProcess(processName, ownerID string) error {
 	query := `
 		UPDATE process_control 
-		SET is_running = false, last_execution = NOW(), updated_at = NOW()
+		SET is_running = false, owner_id = NULL, lease_expires_at = NULL,
+			last_execution = NOW(), updated_at = NOW()
+		WHERE process_name = $1 AND owner_id = $2`
+
+	result, err := r.db.Exec(query, processName, ownerID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("process %s: %w", processName, ErrLockLost)
+	}
+
+	return nil
+}
+
+// ForceStopProcess releases the lock regardless of its owner. It reports
+// whether the process exists.
+func (r *ProcessControlRepository) ForceStopProcess(processName string) (bool, error) {
+	query := `
+		UPDATE process_control 
+		SET is_running = false, owner_id = NULL, lease_expires_at = NULL, updated_at = NOW()
 		WHERE process_name = $1`
-	
-	_, err := r.db.Exec(query, processName)
-	return err
+
+	result, err := r.db.Exec(query, processName)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
 }
 
-func (r *ProcessControlRepository) ForceStopProcess(processName string) error {
+// UpdateProcessSchedule stores the scheduler settings of a process. It
+// reports whether the process exists.
+func (r *ProcessControlRepository) UpdateProcessSchedule(processName string, enabled bool, schedule string, intervalMinutes int) (bool, error) {
 	query := `
 		UPDATE process_control 
-		SET is_running = false, updated_at = NOW()
+		SET enabled = $2, schedule = NULLIF($3, ''), interval_minutes = $4, updated_at = NOW()
 		WHERE process_name = $1`
-	
-	_, err := r.db.Exec(query, processName)
-	return err
+
+	result, err := r.db.Exec(query, processName, enabled, schedule, intervalMinutes)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetCheckpoint returns the pagination token stored by the last run that did
+// not complete, or an empty string when the next run should start fresh.
+func (r *ProcessControlRepository) GetCheckpoint(processName string) (string, error) {
+	query := `SELECT COALESCE(checkpoint, '') FROM process_control WHERE process_name = $1`
+
+	var checkpoint string
+	err := r.db.QueryRow(query, processName).Scan(&checkpoint)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	return checkpoint, err
+}
+
+// SaveCheckpoint stores the page token to resume from. Like the heartbeat
+// it is fenced by the lease, so a run that lost its lock cannot overwrite
+// the checkpoint of the new holder; it returns ErrLockLost instead.
+func (r *ProcessControlRepository) SaveCheckpoint(processName, ownerID, checkpoint string) error {
+	query := `
+		UPDATE process_control 
+		SET checkpoint = $3, checkpoint_updated_at = NOW(), updated_at = NOW()
+		WHERE process_name = $1 AND owner_id = $2 AND is_running = true`
+
+	return r.execFenced(processName, query, processName, ownerID, checkpoint)
+}
+
+// ClearCheckpoint removes the checkpoint so the next run starts fresh. It
+// is fenced by the lease like SaveCheckpoint.
+func (r *ProcessControlRepository) ClearCheckpoint(processName, ownerID string) error {
+	query := `
+		UPDATE process_control 
+		SET checkpoint = NULL, checkpoint_updated_at = NOW(), updated_at = NOW()
+		WHERE process_name = $1 AND owner_id = $2 AND is_running = true`
+
+	return r.execFenced(processName, query, processName, ownerID)
+}
+
+// execFenced runs an update guarded by the lease owner and returns
+// ErrLockLost when it matched no row.
+func (r *ProcessControlRepository) execFenced(processName, query string, args ...any) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("process %s: %w", processName, ErrLockLost)
+	}
+
+	return nil
 }
 
 // Convenience methods for specific processes
@@ -113,14 +294,14 @@ func (r *ProcessControlRepository) CanStartStockSync() (bool, error) {
 	return r.CanStartProcess(ProcessStockSync)
 }
 
-func (r *ProcessControlRepository) StartStockSync() error {
-	return r.StartProcess(ProcessStockSync)
+func (r *ProcessControlRepository) GetStockSyncCheckpoint() (string, error) {
+	return r.GetCheckpoint(ProcessStockSync)
 }
 
-func (r *ProcessControlRepository) FinishStockSync() error {
-	return r.FinishProcess(ProcessStockSync)
+func (r *ProcessControlRepository) SaveStockSyncCheckpoint(ownerID, checkpoint string) error {
+	return r.SaveCheckpoint(ProcessStockSync, ownerID, checkpoint)
 }
 
-func (r *ProcessControlRepository) ForceStopStockSync() error {
-	return r.ForceStopProcess(ProcessStockSync)
-}
\ No newline at end of file
+func (r *ProcessControlRepository) ClearStockSyncCheckpoint(ownerID string) error {
+	return r.ClearCheckpoint(ProcessStockSync, ownerID)
+}
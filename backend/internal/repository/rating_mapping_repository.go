@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"stock-api/internal/models"
+	"stock-api/internal/ratings"
+)
+
+// ratingResolveBatchSize is how many analyses each re-resolution pass reads.
+const ratingResolveBatchSize = 1000
+
+type RatingMappingRepository struct {
+	db *sql.DB
+}
+
+func NewRatingMappingRepository(db *sql.DB) *RatingMappingRepository {
+	return &RatingMappingRepository{db: db}
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// ratingResolver maps raw ratings to canonical values, preferring a mapping
+// for the analysis' brokerage over the global one.
+type ratingResolver struct {
+	global      map[string]int
+	byBrokerage map[string]map[string]int
+}
+
+func loadRatingResolver(q queryer) (*ratingResolver, error) {
+	rows, err := q.Query(`SELECT brokerage, raw_rating, canonical_value FROM rating_mappings`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rating mappings: %w", err)
+	}
+	defer rows.Close()
+
+	resolver := &ratingResolver{global: map[string]int{}, byBrokerage: map[string]map[string]int{}}
+	for rows.Next() {
+		var brokerage, rawRating string
+		var value int
+		if err := rows.Scan(&brokerage, &rawRating, &value); err != nil {
+			return nil, err
+		}
+		key := brokerageKey(brokerage)
+		if key == "" {
+			resolver.global[rawRating] = value
+			continue
+		}
+		if resolver.byBrokerage[key] == nil {
+			resolver.byBrokerage[key] = map[string]int{}
+		}
+		resolver.byBrokerage[key][rawRating] = value
+	}
+
+	return resolver, rows.Err()
+}
+
+// resolve returns the canonical value of rawRating, or nil if it is empty or
+// unmapped.
+func (r *ratingResolver) resolve(brokerage, rawRating string) *int {
+	raw := ratings.Normalize(rawRating)
+	if raw == "" {
+		return nil
+	}
+	if value, ok := r.byBrokerage[brokerageKey(brokerage)][raw]; ok {
+		return &value
+	}
+	if value, ok := r.global[raw]; ok {
+		return &value
+	}
+	return nil
+}
+
+func brokerageKey(brokerage string) string {
+	return strings.ToLower(strings.TrimSpace(brokerage))
+}
+
+// setCanonicalRatings fills the canonical ratings and their labels of a
+// scanned analysis.
+func setCanonicalRatings(analysis *models.StockAnalysis, from, to sql.NullInt64) {
+	if from.Valid {
+		value := int(from.Int64)
+		analysis.RatingFromCanonical = &value
+		analysis.RatingFromLabel = ratings.Label(value)
+	}
+	if to.Valid {
+		value := int(to.Int64)
+		analysis.RatingToCanonical = &value
+		analysis.RatingToLabel = ratings.Label(value)
+	}
+}
+
+const ratingMappingColumns = `id, brokerage, raw_rating, canonical_value, created_at, updated_at`
+
+func scanRatingMapping(row rowScanner) (*models.RatingMapping, error) {
+	mapping := &models.RatingMapping{}
+	err := row.Scan(
+		&mapping.ID, &mapping.Brokerage, &mapping.RawRating, &mapping.CanonicalValue,
+		&mapping.CreatedAt, &mapping.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	mapping.CanonicalLabel = ratings.Label(mapping.CanonicalValue)
+	return mapping, nil
+}
+
+// List returns the mappings ordered by brokerage (global ones first), then
+// canonical value and raw rating. A non-empty brokerage limits the result to
+// that brokerage's mappings.
+func (r *RatingMappingRepository) List(brokerage string) ([]models.RatingMapping, error) {
+	query := `SELECT ` + ratingMappingColumns + ` FROM rating_mappings`
+	args := []any{}
+	if brokerage != "" {
+		query += ` WHERE LOWER(brokerage) = LOWER($1)`
+		args = append(args, strings.TrimSpace(brokerage))
+	}
+	query += ` ORDER BY brokerage, canonical_value DESC, raw_rating`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rating mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []models.RatingMapping{}
+	for rows.Next() {
+		mapping, err := scanRatingMapping(rows)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, *mapping)
+	}
+
+	return mappings, rows.Err()
+}
+
+// Upsert creates the mapping for its brokerage and raw rating, or changes the
+// canonical value of the existing one.
+func (r *RatingMappingRepository) Upsert(brokerage, rawRating string, value int) (*models.RatingMapping, error) {
+	query := `
+		INSERT INTO rating_mappings (brokerage, raw_rating, canonical_value)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (brokerage, raw_rating) DO UPDATE SET
+			canonical_value = EXCLUDED.canonical_value,
+			updated_at = NOW()
+		RETURNING ` + ratingMappingColumns
+
+	mapping, err := scanRatingMapping(r.db.QueryRow(query, strings.TrimSpace(brokerage), ratings.Normalize(rawRating), value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save rating mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// Delete removes a mapping and returns it, or nil if it does not exist.
+func (r *RatingMappingRepository) Delete(id int) (*models.RatingMapping, error) {
+	query := `DELETE FROM rating_mappings WHERE id = $1 RETURNING ` + ratingMappingColumns
+
+	mapping, err := scanRatingMapping(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete rating mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// GetUnmapped lists the raw ratings of stored analyses that no mapping
+// resolves, most frequent first.
+func (r *RatingMappingRepository) GetUnmapped(limit int) ([]models.UnmappedRating, error) {
+	query := `
+		SELECT brokerage, raw_rating, COUNT(*), MAX(analysis_date)
+		FROM (
+			SELECT COALESCE(brokerage, '') AS brokerage, LOWER(TRIM(rating_from)) AS raw_rating, analysis_date
+			FROM stock_analysis
+			WHERE rating_from_canonical IS NULL AND TRIM(COALESCE(rating_from, '')) <> ''
+			UNION ALL
+			SELECT COALESCE(brokerage, ''), LOWER(TRIM(rating_to)), analysis_date
+			FROM stock_analysis
+			WHERE rating_to_canonical IS NULL AND TRIM(COALESCE(rating_to, '')) <> ''
+		) unmapped
+		GROUP BY brokerage, raw_rating
+		ORDER BY COUNT(*) DESC, raw_rating, brokerage
+		LIMIT $1`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unmapped ratings: %w", err)
+	}
+	defer rows.Close()
+
+	unmapped := []models.UnmappedRating{}
+	for rows.Next() {
+		var rating models.UnmappedRating
+		if err := rows.Scan(&rating.Brokerage, &rating.RawRating, &rating.Occurrences, &rating.LastSeen); err != nil {
+			return nil, err
+		}
+		unmapped = append(unmapped, rating)
+	}
+
+	return unmapped, rows.Err()
+}
+
+// ResolveStoredRatings re-resolves the canonical ratings of the analyses in
+// stock_analysis against the current mappings and rewrites the ones that
+// changed, walking them in id order. With onlyUnresolved it only looks at
+// analyses that have a raw rating but no canonical one. It returns the number
+// of analyses rewritten and the IDs of their stocks.
+func (r *RatingMappingRepository) ResolveStoredRatings(onlyUnresolved bool) (int, []int, error) {
+	filter := ""
+	if onlyUnresolved {
+		filter = `AND ((rating_from_canonical IS NULL AND TRIM(COALESCE(rating_from, '')) <> '')
+			OR (rating_to_canonical IS NULL AND TRIM(COALESCE(rating_to, '')) <> ''))`
+	}
+
+	return r.resolveRatingsIn("stock_analysis", filter)
+}
+
+// ResolveArchivedRatings is ResolveStoredRatings for the analyses moved to
+// stock_analysis_archive by the retention policy.
+func (r *RatingMappingRepository) ResolveArchivedRatings() (int, error) {
+	updated, _, err := r.resolveRatingsIn("stock_analysis_archive", "")
+	return updated, err
+}
+
+func (r *RatingMappingRepository) resolveRatingsIn(table, filter string) (int, []int, error) {
+	resolver, err := loadRatingResolver(r.db)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	updated := 0
+	touched := map[int]struct{}{}
+	lastID := 0
+
+	for {
+		rows, err := r.db.Query(`
+			SELECT id, stock_id, COALESCE(brokerage, ''), COALESCE(rating_from, ''), COALESCE(rating_to, ''),
+				rating_from_canonical, rating_to_canonical
+			FROM `+table+`
+			WHERE id > $1 `+filter+`
+			ORDER BY id
+			LIMIT $2`, lastID, ratingResolveBatchSize)
+		if err != nil {
+			return updated, nil, fmt.Errorf("failed to read analyses to resolve: %w", err)
+		}
+
+		read := 0
+		placeholders := []string{}
+		args := []any{}
+		for rows.Next() {
+			var id, stockID int
+			var brokerage, ratingFrom, ratingTo string
+			var storedFrom, storedTo sql.NullInt64
+			if err := rows.Scan(&id, &stockID, &brokerage, &ratingFrom, &ratingTo, &storedFrom, &storedTo); err != nil {
+				rows.Close()
+				return updated, nil, err
+			}
+			read++
+			lastID = id
+
+			from := resolver.resolve(brokerage, ratingFrom)
+			to := resolver.resolve(brokerage, ratingTo)
+			if sameCanonical(storedFrom, from) && sameCanonical(storedTo, to) {
+				continue
+			}
+
+			n := len(args)
+			placeholders = append(placeholders, fmt.Sprintf("($%d::INT, $%d::INT, $%d::INT)", n+1, n+2, n+3))
+			args = append(args, id, from, to)
+			touched[stockID] = struct{}{}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, nil, err
+		}
+
+		if len(placeholders) > 0 {
+			query := `
+				UPDATE ` + table + ` AS sa SET
+					rating_from_canonical = v.rating_from_canonical,
+					rating_to_canonical = v.rating_to_canonical
+				FROM (VALUES ` + strings.Join(placeholders, ", ") + `)
+					AS v(id, rating_from_canonical, rating_to_canonical)
+				WHERE sa.id = v.id`
+
+			if _, err := r.db.Exec(query, args...); err != nil {
+				return updated, nil, fmt.Errorf("failed to update canonical ratings: %w", err)
+			}
+			updated += len(placeholders)
+		}
+
+		if read < ratingResolveBatchSize {
+			break
+		}
+	}
+
+	stockIDs := make([]int, 0, len(touched))
+	for id := range touched {
+		stockIDs = append(stockIDs, id)
+	}
+	sort.Ints(stockIDs)
+
+	return updated, stockIDs, nil
+}
+
+func sameCanonical(stored sql.NullInt64, resolved *int) bool {
+	if !stored.Valid || resolved == nil {
+		return !stored.Valid && resolved == nil
+	}
+	return int(stored.Int64) == *resolved
+}
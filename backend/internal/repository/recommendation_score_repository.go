@@ -2,6 +2,8 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"stock-api/internal/models"
@@ -15,14 +17,40 @@ func NewRecommendationScoreRepository(db *sql.DB) *RecommendationScoreRepository
 	return &RecommendationScoreRepository{db: db}
 }
 
+// encodeBreakdown turns a score breakdown into the JSON stored in the
+// breakdown column.
+func encodeBreakdown(breakdown []models.FactorScore) (string, error) {
+	if breakdown == nil {
+		breakdown = []models.FactorScore{}
+	}
+	data, err := json.Marshal(breakdown)
+	if err != nil {
+		return "", fmt.Errorf("error encoding score breakdown: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeBreakdown reads the breakdown column, which is NULL for scores
+// stored before it existed.
+func decodeBreakdown(data []byte) ([]models.FactorScore, error) {
+	breakdown := []models.FactorScore{}
+	if len(data) == 0 {
+		return breakdown, nil
+	}
+	if err := json.Unmarshal(data, &breakdown); err != nil {
+		return nil, fmt.Errorf("error decoding score breakdown: %w", err)
+	}
+	return breakdown, nil
+}
+
 func (r *RecommendationScoreRepository) UpsertRecommendationScore(score *models.RecommendationScore) error {
 	query := `
 		INSERT INTO recommendation_scores (
-			stock_id, total_score, rating_score, rating_change_score, 
+			stock_id, strategy, total_score, rating_score, rating_change_score, 
 			target_change_score, action_score, coverage_score, 
-			confidence, reason, latest_analysis_id, calculated_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)
-		ON CONFLICT (stock_id) DO UPDATE SET
+			confidence, reason, latest_analysis_id, breakdown, profile_version, calculated_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $14)
+		ON CONFLICT (stock_id, strategy) DO UPDATE SET
 			total_score = EXCLUDED.total_score,
 			rating_score = EXCLUDED.rating_score,
 			rating_change_score = EXCLUDED.rating_change_score,
@@ -32,17 +60,25 @@ func (r *RecommendationScoreRepository) UpsertRecommendationScore(score *models.
 			confidence = EXCLUDED.confidence,
 			reason = EXCLUDED.reason,
 			latest_analysis_id = EXCLUDED.latest_analysis_id,
+			breakdown = EXCLUDED.breakdown,
+			profile_version = EXCLUDED.profile_version,
 			calculated_at = EXCLUDED.calculated_at,
 			updated_at = EXCLUDED.updated_at
 		RETURNING id, created_at`
 
+	breakdown, err := encodeBreakdown(score.Breakdown)
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
 	score.CalculatedAt = now
 	score.UpdatedAt = now
 
-	err := r.db.QueryRow(
+	err = r.db.QueryRow(
 		query,
 		score.StockID,
+		score.Strategy,
 		score.TotalScore,
 		score.RatingScore,
 		score.RatingChangeScore,
@@ -52,6 +88,8 @@ func (r *RecommendationScoreRepository) UpsertRecommendationScore(score *models.
 		score.Confidence,
 		score.Reason,
 		score.LatestAnalysisID,
+		breakdown,
+		score.ProfileVersion,
 		now,
 	).Scan(&score.ID, &score.CreatedAt)
 
@@ -59,7 +97,62 @@ func (r *RecommendationScoreRepository) UpsertRecommendationScore(score *models.
 }
 
 
-func (r *RecommendationScoreRepository) GetTopRecommendationsPaginated(page, pageSize int) (*models.PaginatedResponse[models.RecommendationWithStock], error) {
+// UpsertRecommendationScores stores many scores with multi-row upserts.
+func (r *RecommendationScoreRepository) UpsertRecommendationScores(scores []models.RecommendationScore) error {
+	now := time.Now()
+
+	for start := 0; start < len(scores); start += ingestBatchSize {
+		end := start + ingestBatchSize
+		if end > len(scores) {
+			end = len(scores)
+		}
+
+		args := []any{}
+		for i := start; i < end; i++ {
+			score := &scores[i]
+			score.CalculatedAt = now
+			score.UpdatedAt = now
+			breakdown, err := encodeBreakdown(score.Breakdown)
+			if err != nil {
+				return err
+			}
+			args = append(args, score.StockID, score.Strategy, score.TotalScore, score.RatingScore, score.RatingChangeScore,
+				score.TargetChangeScore, score.ActionScore, score.CoverageScore, score.Confidence,
+				score.Reason, score.LatestAnalysisID, breakdown, score.ProfileVersion, now, now)
+		}
+
+		query := `
+			INSERT INTO recommendation_scores (
+				stock_id, strategy, total_score, rating_score, rating_change_score,
+				target_change_score, action_score, coverage_score,
+				confidence, reason, latest_analysis_id, breakdown, profile_version, calculated_at, updated_at
+			) VALUES ` + valuesPlaceholders(end-start, 15) + `
+			ON CONFLICT (stock_id, strategy) DO UPDATE SET
+				total_score = EXCLUDED.total_score,
+				rating_score = EXCLUDED.rating_score,
+				rating_change_score = EXCLUDED.rating_change_score,
+				target_change_score = EXCLUDED.target_change_score,
+				action_score = EXCLUDED.action_score,
+				coverage_score = EXCLUDED.coverage_score,
+				confidence = EXCLUDED.confidence,
+				reason = EXCLUDED.reason,
+				latest_analysis_id = EXCLUDED.latest_analysis_id,
+				breakdown = EXCLUDED.breakdown,
+				profile_version = EXCLUDED.profile_version,
+				calculated_at = EXCLUDED.calculated_at,
+				updated_at = EXCLUDED.updated_at`
+
+		if _, err := r.db.Exec(query, args...); err != nil {
+			return fmt.Errorf("error upserting recommendation scores: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetTopRecommendationsPaginated ranks the stocks by their score under one
+// strategy.
+func (r *RecommendationScoreRepository) GetTopRecommendationsPaginated(page, pageSize int, strategy string) (*models.PaginatedResponse[models.RecommendationWithStock], error) {
 	if page < 1 {
 		page = 1
 	}
@@ -68,9 +161,9 @@ func (r *RecommendationScoreRepository) GetTopRecommendationsPaginated(page, pag
 	}
 
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM recommendation_scores`
+	countQuery := `SELECT COUNT(*) FROM recommendation_scores WHERE strategy = $1`
 	var totalItems int
-	err := r.db.QueryRow(countQuery).Scan(&totalItems)
+	err := r.db.QueryRow(countQuery, strategy).Scan(&totalItems)
 	if err != nil {
 		return nil, err
 	}
@@ -86,16 +179,17 @@ func (r *RecommendationScoreRepository) GetTopRecommendationsPaginated(page, pag
 	// Get paginated data
 	query := `
 		SELECT 
-			rs.id, rs.stock_id, rs.total_score, rs.rating_score, rs.rating_change_score,
+			rs.id, rs.stock_id, rs.strategy, rs.total_score, rs.rating_score, rs.rating_change_score,
 			rs.target_change_score, rs.action_score, rs.coverage_score, rs.confidence,
-			rs.reason, rs.latest_analysis_id, rs.calculated_at, rs.created_at, rs.updated_at,
+			rs.reason, rs.latest_analysis_id, rs.breakdown, COALESCE(rs.profile_version, ''), rs.calculated_at, rs.created_at, rs.updated_at,
 			s.id, s.symbol, s.name, s.created_at, s.updated_at
 		FROM recommendation_scores rs
 		JOIN stocks s ON rs.stock_id = s.id
+		WHERE rs.strategy = $1
 		ORDER BY rs.total_score DESC
-		LIMIT $1 OFFSET $2`
+		LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.Query(query, pageSize, offset)
+	rows, err := r.db.Query(query, strategy, pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -107,16 +201,20 @@ func (r *RecommendationScoreRepository) GetTopRecommendationsPaginated(page, pag
 	for rows.Next() {
 		var rec models.RecommendationWithStock
 		var stock models.Stock
+		var breakdown []byte
 
 		err := rows.Scan(
-			&rec.ID, &rec.StockID, &rec.TotalScore, &rec.RatingScore, &rec.RatingChangeScore,
+			&rec.ID, &rec.StockID, &rec.Strategy, &rec.TotalScore, &rec.RatingScore, &rec.RatingChangeScore,
 			&rec.TargetChangeScore, &rec.ActionScore, &rec.CoverageScore, &rec.Confidence,
-			&rec.Reason, &rec.LatestAnalysisID, &rec.CalculatedAt, &rec.CreatedAt, &rec.UpdatedAt,
+			&rec.Reason, &rec.LatestAnalysisID, &breakdown, &rec.ProfileVersion, &rec.CalculatedAt, &rec.CreatedAt, &rec.UpdatedAt,
 			&stock.ID, &stock.Symbol, &stock.Name, &stock.CreatedAt, &stock.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if rec.Breakdown, err = decodeBreakdown(breakdown); err != nil {
+			return nil, err
+		}
 
 		// Get latest analysis for this stock
 		stockWithAnalysis := models.StockWithAnalysis{Stock: stock}
@@ -151,29 +249,54 @@ func (r *RecommendationScoreRepository) DeleteRecommendationScore(stockID int) e
 	return err
 }
 
-func (r *RecommendationScoreRepository) GetRecommendationScoreByStockID(stockID int) (*models.RecommendationScore, error) {
+func (r *RecommendationScoreRepository) GetRecommendationScoreByStockID(stockID int, strategy string) (*models.RecommendationScore, error) {
 	query := `
-		SELECT id, stock_id, total_score, rating_score, rating_change_score,
+		SELECT id, stock_id, strategy, total_score, rating_score, rating_change_score,
 			   target_change_score, action_score, coverage_score, confidence,
-			   reason, latest_analysis_id, calculated_at, created_at, updated_at
+			   reason, latest_analysis_id, breakdown, COALESCE(profile_version, ''), calculated_at, created_at, updated_at
 		FROM recommendation_scores 
-		WHERE stock_id = $1`
+		WHERE stock_id = $1 AND strategy = $2`
 
 	var score models.RecommendationScore
-	err := r.db.QueryRow(query, stockID).Scan(
-		&score.ID, &score.StockID, &score.TotalScore, &score.RatingScore, &score.RatingChangeScore,
+	var breakdown []byte
+	err := r.db.QueryRow(query, stockID, strategy).Scan(
+		&score.ID, &score.StockID, &score.Strategy, &score.TotalScore, &score.RatingScore, &score.RatingChangeScore,
 		&score.TargetChangeScore, &score.ActionScore, &score.CoverageScore, &score.Confidence,
-		&score.Reason, &score.LatestAnalysisID, &score.CalculatedAt, &score.CreatedAt, &score.UpdatedAt,
+		&score.Reason, &score.LatestAnalysisID, &breakdown, &score.ProfileVersion, &score.CalculatedAt, &score.CreatedAt, &score.UpdatedAt,
 	)
 
 	if err != nil {
 		return nil, err
 	}
+	if score.Breakdown, err = decodeBreakdown(breakdown); err != nil {
+		return nil, err
+	}
 
 	return &score, nil
 }
 
-func (r *RecommendationScoreRepository) GetRecommendationStats() (map[string]interface{}, error) {
+// CountScoresByStrategy returns how many stored scores each strategy has.
+// Strategies without any are missing from the map.
+func (r *RecommendationScoreRepository) CountScoresByStrategy() (map[string]int, error) {
+	rows, err := r.db.Query(`SELECT strategy, COUNT(*) FROM recommendation_scores GROUP BY strategy`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var strategy string
+		var count int
+		if err := rows.Scan(&strategy, &count); err != nil {
+			return nil, err
+		}
+		counts[strategy] = count
+	}
+	return counts, rows.Err()
+}
+
+func (r *RecommendationScoreRepository) GetRecommendationStats(strategy string) (map[string]interface{}, error) {
 	query := `
 		SELECT 
 			COUNT(*) as total_recommendations,
@@ -181,12 +304,13 @@ func (r *RecommendationScoreRepository) GetRecommendationStats() (map[string]int
 			COUNT(CASE WHEN confidence = 'Medium' THEN 1 END) as medium_confidence,
 			COUNT(CASE WHEN confidence = 'Low' THEN 1 END) as low_confidence,
 			AVG(total_score) as avg_score
-		FROM recommendation_scores`
+		FROM recommendation_scores
+		WHERE strategy = $1`
 
 	var totalRecs, highConf, mediumConf, lowConf int
 	var avgScore sql.NullFloat64
 
-	err := r.db.QueryRow(query).Scan(&totalRecs, &highConf, &mediumConf, &lowConf, &avgScore)
+	err := r.db.QueryRow(query, strategy).Scan(&totalRecs, &highConf, &mediumConf, &lowConf, &avgScore)
 	if err != nil {
 		return nil, err
 	}
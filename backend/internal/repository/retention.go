@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"stock-api/internal/models"
+)
+
+const ProcessAnalysisRetention = "analysis_retention"
+
+// Retention modes.
+const (
+	RetentionKeepCount = "count"
+	RetentionKeepAge   = "age"
+	RetentionKeepAll   = "all"
+)
+
+// RetentionPolicy decides which analyses of a stock stay in stock_analysis.
+// The others are moved to stock_analysis_archive rather than deleted.
+type RetentionPolicy struct {
+	Mode string
+	// KeepCount is how many of the newest analyses are kept in count mode.
+	KeepCount int
+	// KeepAge is how old an analysis may get in age mode. The newest
+	// analysis of a stock is kept regardless, so every stock keeps a current
+	// rating.
+	KeepAge time.Duration
+}
+
+// NewRetentionPolicy validates a retention policy read from configuration.
+func NewRetentionPolicy(mode string, keepCount int, keepAge time.Duration) (RetentionPolicy, error) {
+	policy := RetentionPolicy{Mode: mode, KeepCount: keepCount, KeepAge: keepAge}
+
+	switch mode {
+	case RetentionKeepCount:
+		if keepCount < 1 {
+			return policy, fmt.Errorf("retention keep count must be at least 1, got %d", keepCount)
+		}
+	case RetentionKeepAge:
+		if keepAge <= 0 {
+			return policy, fmt.Errorf("retention keep age must be positive, got %s", keepAge)
+		}
+	case RetentionKeepAll:
+	default:
+		return policy, fmt.Errorf("unknown retention mode %q, expected %s, %s or %s", mode, RetentionKeepCount, RetentionKeepAge, RetentionKeepAll)
+	}
+
+	return policy, nil
+}
+
+func (p RetentionPolicy) String() string {
+	switch p.Mode {
+	case RetentionKeepCount:
+		return fmt.Sprintf("keep the newest %d analyses per stock", p.KeepCount)
+	case RetentionKeepAge:
+		return fmt.Sprintf("keep analyses newer than %s", p.KeepAge)
+	default:
+		return "keep all analyses"
+	}
+}
+
+// analysisColumns are the columns shared by stock_analysis and
+// stock_analysis_archive.
+const analysisColumns = `id, stock_id, target_from, target_to, target_from_value, target_to_value,
+	target_currency, target_parse_status, action, action_type, brokerage, rating_from, rating_to,
+	rating_from_canonical, rating_to_canonical, analysis_date, created_at`
+
+// archiveAnalyses moves the analyses of stockIDs that policy no longer keeps
+// to stock_analysis_archive and returns how many it moved and from which
+// stocks. Analyses still referenced by a recommendation score stay put.
+func archiveAnalyses(tx *sql.Tx, stockIDs []int, policy RetentionPolicy) (int, []int, error) {
+	var condition string
+	var bound any
+
+	switch policy.Mode {
+	case RetentionKeepCount:
+		condition = `rn > $2`
+		bound = policy.KeepCount
+	case RetentionKeepAge:
+		condition = `rn > 1 AND analysis_date < $2`
+		bound = time.Now().UTC().Add(-policy.KeepAge)
+	default:
+		return 0, nil, nil
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, stock_id FROM (
+			SELECT id, stock_id, analysis_date, ROW_NUMBER() OVER (PARTITION BY stock_id ORDER BY analysis_date DESC, id DESC) AS rn
+			FROM stock_analysis
+			WHERE stock_id = ANY($1)
+		) ranked
+		WHERE `+condition+`
+		AND id NOT IN (
+			SELECT latest_analysis_id FROM recommendation_scores
+			WHERE stock_id = ANY($1) AND latest_analysis_id IS NOT NULL
+		)`, intArray(stockIDs), bound)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error selecting analyses to archive: %w", err)
+	}
+	var ids, archivedFrom []int
+	seen := make(map[int]bool)
+	for rows.Next() {
+		var id, stockID int
+		if err := rows.Scan(&id, &stockID); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		ids = append(ids, id)
+		if !seen[stockID] {
+			seen[stockID] = true
+			archivedFrom = append(archivedFrom, stockID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	if len(ids) == 0 {
+		return 0, nil, nil
+	}
+
+	archiveQuery := `
+		INSERT INTO stock_analysis_archive (` + analysisColumns + `)
+		SELECT ` + analysisColumns + ` FROM stock_analysis WHERE id = ANY($1)
+		ON CONFLICT (stock_id, analysis_date, brokerage) DO UPDATE SET
+			target_from = EXCLUDED.target_from,
+			target_to = EXCLUDED.target_to,
+			target_from_value = EXCLUDED.target_from_value,
+			target_to_value = EXCLUDED.target_to_value,
+			target_currency = EXCLUDED.target_currency,
+			target_parse_status = EXCLUDED.target_parse_status,
+			action = EXCLUDED.action,
+			action_type = EXCLUDED.action_type,
+			rating_from = EXCLUDED.rating_from,
+			rating_to = EXCLUDED.rating_to,
+			rating_from_canonical = EXCLUDED.rating_from_canonical,
+			rating_to_canonical = EXCLUDED.rating_to_canonical`
+	if _, err := tx.Exec(archiveQuery, intArray(ids)); err != nil {
+		return 0, nil, fmt.Errorf("error archiving analyses: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM stock_analysis WHERE id = ANY($1)`, intArray(ids)); err != nil {
+		return 0, nil, fmt.Errorf("error removing archived analyses: %w", err)
+	}
+
+	return len(ids), archivedFrom, nil
+}
+
+// ArchiveAnalyses applies policy to the given stocks in one transaction and
+// returns how many analyses it archived and from which stocks. Like
+// ingestion, it is retried when it loses a serialization conflict.
+func (r *StockRepository) ArchiveAnalyses(stockIDs []int, policy RetentionPolicy) (int, []int, error) {
+	if policy.Mode == RetentionKeepAll || len(stockIDs) == 0 {
+		return 0, nil, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		archived, archivedFrom, err := r.archiveInTx(stockIDs, policy)
+		if err == nil {
+			return archived, archivedFrom, nil
+		}
+		if attempt >= ingestMaxAttempts || !isRetryableTxError(err) {
+			return 0, nil, err
+		}
+		time.Sleep(time.Duration(attempt*attempt) * 20 * time.Millisecond)
+	}
+}
+
+func (r *StockRepository) archiveInTx(stockIDs []int, policy RetentionPolicy) (int, []int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin archive transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	archived, archivedFrom, err := archiveAnalyses(tx, stockIDs, policy)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit archive transaction: %w", err)
+	}
+	return archived, archivedFrom, nil
+}
+
+// GetArchivedAnalysisForStock returns up to limit archived analyses of a
+// stock, newest first.
+func (r *StockRepository) GetArchivedAnalysisForStock(stockID int, limit int) ([]models.StockAnalysis, error) {
+	query := `
+		SELECT id, stock_id, COALESCE(target_from, ''), COALESCE(target_to, ''), target_from_value, target_to_value,
+			COALESCE(target_currency, ''), target_parse_status,
+			COALESCE(action, ''), COALESCE(action_type, ''), COALESCE(brokerage, ''),
+			COALESCE(rating_from, ''), COALESCE(rating_to, ''), rating_from_canonical, rating_to_canonical,
+			analysis_date, COALESCE(created_at, archived_at)
+		FROM stock_analysis_archive
+		WHERE stock_id = $1
+		ORDER BY analysis_date DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(query, stockID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archived analyses: %w", err)
+	}
+	defer rows.Close()
+
+	analyses := []models.StockAnalysis{}
+	for rows.Next() {
+		var analysis models.StockAnalysis
+		var targetFromValue, targetToValue sql.NullFloat64
+		var ratingFromCanonical, ratingToCanonical sql.NullInt64
+		err := rows.Scan(
+			&analysis.ID, &analysis.StockID, &analysis.TargetFrom, &analysis.TargetTo,
+			&targetFromValue, &targetToValue, &analysis.TargetCurrency, &analysis.TargetParseStatus,
+			&analysis.Action, &analysis.ActionType, &analysis.Brokerage, &analysis.RatingFrom, &analysis.RatingTo,
+			&ratingFromCanonical, &ratingToCanonical, &analysis.AnalysisDate, &analysis.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		analysis.TargetFromValue = nullFloatPtr(targetFromValue)
+		analysis.TargetToValue = nullFloatPtr(targetToValue)
+		setCanonicalRatings(&analysis, ratingFromCanonical, ratingToCanonical)
+		analysis.Archived = true
+		analyses = append(analyses, analysis)
+	}
+
+	return analyses, rows.Err()
+}
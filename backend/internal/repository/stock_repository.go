@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"stock-api/internal/actions"
 	"stock-api/internal/models"
 )
 
@@ -22,45 +23,6 @@ func (r *StockRepository) DB() *sql.DB {
 	return r.db
 }
 
-func (r *StockRepository) CreateStock(stock *models.Stock) error {
-	// First try to get existing stock
-	existing, err := r.GetStockBySymbol(stock.Symbol)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("error checking existing stock %s: %w", stock.Symbol, err)
-	}
-
-	if existing != nil {
-		// Stock exists, update it and return the existing data
-		updateQuery := `
-			UPDATE stocks 
-			SET name = $1, updated_at = NOW() 
-			WHERE symbol = $2
-			RETURNING id, created_at, updated_at`
-
-		err := r.db.QueryRow(updateQuery, stock.Name, stock.Symbol).
-			Scan(&stock.ID, &stock.CreatedAt, &stock.UpdatedAt)
-		if err != nil {
-			return fmt.Errorf("error updating stock %s: %w", stock.Symbol, err)
-		}
-		return nil
-	}
-
-	// Stock doesn't exist, create new one
-	insertQuery := `
-		INSERT INTO stocks (symbol, name)
-		VALUES ($1, $2)
-		RETURNING id, created_at, updated_at`
-
-	err = r.db.QueryRow(insertQuery, stock.Symbol, stock.Name).
-		Scan(&stock.ID, &stock.CreatedAt, &stock.UpdatedAt)
-
-	if err != nil {
-		return fmt.Errorf("error inserting stock %s: %w", stock.Symbol, err)
-	}
-
-	return nil
-}
-
 func (r *StockRepository) GetStockBySymbol(symbol string) (*models.Stock, error) {
 	query := `
 		SELECT id, symbol, name, created_at, updated_at
@@ -79,63 +41,12 @@ func (r *StockRepository) GetStockBySymbol(symbol string) (*models.Stock, error)
 }
 
 
-func (r *StockRepository) CreateStockAnalysis(analysis *models.StockAnalysis) error {
-	// First check if analysis already exists
-	checkQuery := `
-		SELECT id, created_at FROM stock_analysis 
-		WHERE stock_id = $1 AND analysis_date = $2 AND brokerage = $3`
-
-	var existingID int
-	var existingCreatedAt time.Time
-	err := r.db.QueryRow(checkQuery, analysis.StockID, analysis.AnalysisDate, analysis.Brokerage).
-		Scan(&existingID, &existingCreatedAt)
-
-	if err == nil {
-		// Analysis exists, check if we need to update
-		updateQuery := `
-			UPDATE stock_analysis 
-			SET target_from = $1, target_to = $2, action = $3, rating_from = $4, rating_to = $5
-			WHERE stock_id = $6 AND analysis_date = $7 AND brokerage = $8
-			AND (target_from IS DISTINCT FROM $1 OR target_to IS DISTINCT FROM $2 
-				 OR action IS DISTINCT FROM $3 OR rating_from IS DISTINCT FROM $4 
-				 OR rating_to IS DISTINCT FROM $5)
-			RETURNING id, created_at`
-
-		err = r.db.QueryRow(updateQuery, analysis.TargetFrom, analysis.TargetTo, analysis.Action,
-			analysis.RatingFrom, analysis.RatingTo, analysis.StockID, analysis.AnalysisDate, analysis.Brokerage).
-			Scan(&analysis.ID, &analysis.CreatedAt)
-
-		if err == sql.ErrNoRows {
-			// No update needed, use existing values
-			analysis.ID = existingID
-			analysis.CreatedAt = existingCreatedAt
-			return nil
-		}
-		return err
-	} else if err != sql.ErrNoRows {
-		return fmt.Errorf("error checking existing analysis: %w", err)
-	}
-
-	// Analysis doesn't exist, create new one
-	insertQuery := `
-		INSERT INTO stock_analysis (stock_id, target_from, target_to, action, brokerage, rating_from, rating_to, analysis_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, created_at`
-
-	err = r.db.QueryRow(insertQuery, analysis.StockID, analysis.TargetFrom, analysis.TargetTo,
-		analysis.Action, analysis.Brokerage, analysis.RatingFrom, analysis.RatingTo, analysis.AnalysisDate).
-		Scan(&analysis.ID, &analysis.CreatedAt)
-
-	if err != nil {
-		return fmt.Errorf("error inserting analysis for stock_id %d: %w", analysis.StockID, err)
-	}
-
-	return nil
-}
-
 func (r *StockRepository) GetLatestAnalysisForStock(stockID int, limit int) ([]models.StockAnalysis, error) {
 	query := `
-		SELECT id, stock_id, target_from, target_to, action, brokerage, rating_from, rating_to, analysis_date, created_at
+		SELECT id, stock_id, target_from, target_to, target_from_value, target_to_value,
+			COALESCE(target_currency, ''), target_parse_status,
+			action, COALESCE(action_type, ''), brokerage, rating_from, rating_to, rating_from_canonical, rating_to_canonical,
+			analysis_date, created_at
 		FROM stock_analysis
 		WHERE stock_id = $1
 		ORDER BY analysis_date DESC
@@ -150,14 +61,20 @@ func (r *StockRepository) GetLatestAnalysisForStock(stockID int, limit int) ([]m
 	var analyses []models.StockAnalysis
 	for rows.Next() {
 		var analysis models.StockAnalysis
+		var targetFromValue, targetToValue sql.NullFloat64
+		var ratingFromCanonical, ratingToCanonical sql.NullInt64
 		err := rows.Scan(
 			&analysis.ID, &analysis.StockID, &analysis.TargetFrom, &analysis.TargetTo,
-			&analysis.Action, &analysis.Brokerage, &analysis.RatingFrom, &analysis.RatingTo,
-			&analysis.AnalysisDate, &analysis.CreatedAt,
+			&targetFromValue, &targetToValue, &analysis.TargetCurrency, &analysis.TargetParseStatus,
+			&analysis.Action, &analysis.ActionType, &analysis.Brokerage, &analysis.RatingFrom, &analysis.RatingTo,
+			&ratingFromCanonical, &ratingToCanonical, &analysis.AnalysisDate, &analysis.CreatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		analysis.TargetFromValue = nullFloatPtr(targetFromValue)
+		analysis.TargetToValue = nullFloatPtr(targetToValue)
+		setCanonicalRatings(&analysis, ratingFromCanonical, ratingToCanonical)
 		analyses = append(analyses, analysis)
 	}
 
@@ -165,54 +82,113 @@ func (r *StockRepository) GetLatestAnalysisForStock(stockID int, limit int) ([]m
 }
 
 
-func (r *StockRepository) GetStocksWithAnalysisPaginated(page, pageSize int, filters models.StockFilterParams) (*models.PaginatedResponse[models.StockWithAnalysis], error) {
-	if page < 1 {
-		page = 1
+// GetStocksWithLatestAnalysis loads the given stocks together with up to
+// limit of their newest analyses since the given time each, in a single
+// query. Stocks without such analyses are returned without any.
+func (r *StockRepository) GetStocksWithLatestAnalysis(stockIDs []int, since time.Time, limit int) ([]models.StockWithAnalysis, error) {
+	query := `
+		SELECT
+			s.id, s.symbol, s.name, s.created_at, s.updated_at,
+			sa.id, COALESCE(sa.target_from, ''), COALESCE(sa.target_to, ''),
+			sa.target_from_value, sa.target_to_value, COALESCE(sa.target_currency, ''),
+			COALESCE(sa.target_parse_status, ''), COALESCE(sa.action, ''), COALESCE(sa.action_type, ''),
+			COALESCE(sa.brokerage, ''), COALESCE(sa.rating_from, ''), COALESCE(sa.rating_to, ''),
+			sa.rating_from_canonical, sa.rating_to_canonical, sa.analysis_date, sa.created_at
+		FROM stocks s
+		LEFT JOIN LATERAL (
+			SELECT id, target_from, target_to, target_from_value, target_to_value, target_currency, target_parse_status,
+				action, action_type, brokerage, rating_from, rating_to, rating_from_canonical, rating_to_canonical,
+				analysis_date, created_at
+			FROM stock_analysis
+			WHERE stock_id = s.id AND analysis_date >= $3
+			ORDER BY analysis_date DESC
+			LIMIT $2
+		) sa ON true
+		WHERE s.id = ANY($1)
+		ORDER BY s.id, sa.analysis_date DESC`
+
+	rows, err := r.db.Query(query, intArray(stockIDs), limit, since)
+	if err != nil {
+		return nil, err
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	defer rows.Close()
+
+	var result []models.StockWithAnalysis
+	for rows.Next() {
+		var stock models.Stock
+		var analysis models.StockAnalysis
+		var analysisID sql.NullInt64
+		var targetFromValue, targetToValue sql.NullFloat64
+		var ratingFromCanonical, ratingToCanonical sql.NullInt64
+		var analysisDate sql.NullTime
+		var analysisCreatedAt sql.NullTime
+
+		err := rows.Scan(
+			&stock.ID, &stock.Symbol, &stock.Name, &stock.CreatedAt, &stock.UpdatedAt,
+			&analysisID, &analysis.TargetFrom, &analysis.TargetTo,
+			&targetFromValue, &targetToValue, &analysis.TargetCurrency, &analysis.TargetParseStatus, &analysis.Action,
+			&analysis.ActionType, &analysis.Brokerage, &analysis.RatingFrom, &analysis.RatingTo, &ratingFromCanonical, &ratingToCanonical,
+			&analysisDate, &analysisCreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if len(result) == 0 || result[len(result)-1].ID != stock.ID {
+			result = append(result, models.StockWithAnalysis{Stock: stock})
+		}
+
+		if analysisID.Valid {
+			analysis.ID = int(analysisID.Int64)
+			analysis.TargetFromValue = nullFloatPtr(targetFromValue)
+			analysis.TargetToValue = nullFloatPtr(targetToValue)
+			setCanonicalRatings(&analysis, ratingFromCanonical, ratingToCanonical)
+			analysis.StockID = stock.ID
+			analysis.AnalysisDate = analysisDate.Time
+			analysis.CreatedAt = analysisCreatedAt.Time
+			last := &result[len(result)-1]
+			last.LatestAnalysis = append(last.LatestAnalysis, analysis)
+		}
 	}
 
-	// Build where conditions and parameters for filtering
-	whereConditions := []string{}
-	queryArgs := []any{}
-	argIndex := 1
+	return result, rows.Err()
+}
 
-	// Filter by brokerage
-	if filters.Brokerage != "" && filters.Brokerage != "all" {
-		whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND LOWER(sa.brokerage) LIKE LOWER($%d))", argIndex))
-		queryArgs = append(queryArgs, "%"+filters.Brokerage+"%")
-		argIndex++
+func (r *StockRepository) GetAllStockIDs() ([]int, error) {
+	rows, err := r.db.Query(`SELECT id FROM stocks ORDER BY id`)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Filter by action type
-	if filters.ActionType != "" && filters.ActionType != "all" {
-		switch filters.ActionType {
-		case "initiated":
-			whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND LOWER(sa.action) LIKE '%%initiated%%')"))
-		case "raised":
-			whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND LOWER(sa.action) LIKE '%%raised%%')"))
-		case "lowered":
-			whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND LOWER(sa.action) LIKE '%%lowered%%')"))
-		case "upgraded":
-			whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND LOWER(sa.action) LIKE '%%upgraded%%')"))
-		case "downgraded":
-			whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND LOWER(sa.action) LIKE '%%downgraded%%')"))
-		case "reiterated":
-			whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND LOWER(sa.action) LIKE '%%reiterated%%')"))
-		case "target-set":
-			whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND LOWER(sa.action) LIKE '%%target set%%')"))
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
+		ids = append(ids, id)
 	}
 
-	whereClause := ""
-	if len(whereConditions) > 0 {
-		whereClause = "WHERE " + fmt.Sprintf("(%s)", whereConditions[0])
-		for i := 1; i < len(whereConditions); i++ {
-			whereClause += " AND " + fmt.Sprintf("(%s)", whereConditions[i])
-		}
+	return ids, rows.Err()
+}
+
+func (r *StockRepository) GetStocksWithAnalysisPaginated(page, pageSize int, filters models.StockFilterParams) (*models.PaginatedResponse[models.StockWithAnalysis], error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
 	}
 
+	// Build where conditions and parameters for filtering
+	queryArgs := []any{}
+	whereClause := stockFilterWhereClause(filters, func(value any) string {
+		queryArgs = append(queryArgs, value)
+		return fmt.Sprintf("$%d", len(queryArgs))
+	})
+	argIndex := len(queryArgs) + 1
+
 	// Get total count with filters
 	var totalCount int
 	countQuery := fmt.Sprintf("SELECT COUNT(DISTINCT s.id) FROM stocks s %s", whereClause)
@@ -287,12 +263,18 @@ func (r *StockRepository) GetStocksWithAnalysisPaginated(page, pageSize int, fil
 		SELECT 
 			s.id, s.symbol, s.name, s.created_at, s.updated_at, s.max_analysis_date,
 			COALESCE(sa.id, 0) as analysis_id, COALESCE(sa.target_from, '') as target_from, 
-			COALESCE(sa.target_to, '') as target_to, COALESCE(sa.action, '') as action,
+			COALESCE(sa.target_to, '') as target_to, sa.target_from_value, sa.target_to_value,
+			COALESCE(sa.target_currency, '') as target_currency,
+			COALESCE(sa.target_parse_status, '') as target_parse_status, COALESCE(sa.action, '') as action,
+			COALESCE(sa.action_type, '') as action_type,
 			COALESCE(sa.brokerage, '') as brokerage, COALESCE(sa.rating_from, '') as rating_from,
-			COALESCE(sa.rating_to, '') as rating_to, sa.analysis_date, sa.created_at as analysis_created_at
+			COALESCE(sa.rating_to, '') as rating_to, sa.rating_from_canonical, sa.rating_to_canonical,
+			sa.analysis_date, sa.created_at as analysis_created_at
 		FROM paginated_stocks s
 		LEFT JOIN LATERAL (
-			SELECT id, target_from, target_to, action, brokerage, rating_from, rating_to, analysis_date, created_at
+			SELECT id, target_from, target_to, target_from_value, target_to_value, target_currency, target_parse_status,
+				action, action_type, brokerage, rating_from, rating_to, rating_from_canonical, rating_to_canonical,
+				analysis_date, created_at
 			FROM stock_analysis
 			WHERE stock_id = s.id
 			ORDER BY analysis_date DESC
@@ -319,14 +301,18 @@ func (r *StockRepository) GetStocksWithAnalysisPaginated(page, pageSize int, fil
 		var stock models.Stock
 		var analysis models.StockAnalysis
 		var analysisID sql.NullInt64
+		var targetFromValue, targetToValue sql.NullFloat64
+		var ratingFromCanonical, ratingToCanonical sql.NullInt64
 		var analysisDate sql.NullTime
 		var analysisCreatedAt sql.NullTime
 		var maxAnalysisDate sql.NullTime
 
 		err := rows.Scan(
 			&stock.ID, &stock.Symbol, &stock.Name, &stock.CreatedAt, &stock.UpdatedAt, &maxAnalysisDate,
-			&analysisID, &analysis.TargetFrom, &analysis.TargetTo, &analysis.Action,
-			&analysis.Brokerage, &analysis.RatingFrom, &analysis.RatingTo, &analysisDate, &analysisCreatedAt,
+			&analysisID, &analysis.TargetFrom, &analysis.TargetTo,
+			&targetFromValue, &targetToValue, &analysis.TargetCurrency, &analysis.TargetParseStatus, &analysis.Action,
+			&analysis.ActionType, &analysis.Brokerage, &analysis.RatingFrom, &analysis.RatingTo, &ratingFromCanonical, &ratingToCanonical,
+			&analysisDate, &analysisCreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -347,6 +333,9 @@ func (r *StockRepository) GetStocksWithAnalysisPaginated(page, pageSize int, fil
 		// Add analysis if it exists
 		if analysisID.Valid {
 			analysis.ID = int(analysisID.Int64)
+			analysis.TargetFromValue = nullFloatPtr(targetFromValue)
+			analysis.TargetToValue = nullFloatPtr(targetToValue)
+			setCanonicalRatings(&analysis, ratingFromCanonical, ratingToCanonical)
 			analysis.StockID = stock.ID
 			analysis.AnalysisDate = analysisDate.Time
 			analysis.CreatedAt = analysisCreatedAt.Time
@@ -369,57 +358,69 @@ func (r *StockRepository) GetStocksWithAnalysisPaginated(page, pageSize int, fil
 	}, nil
 }
 
-func (r *StockRepository) DeleteOldAnalysis(stockID int, keepCount int) error {
-	query := `
-		DELETE FROM stock_analysis 
-		WHERE stock_id = $1 
-		AND id NOT IN (
-			SELECT id FROM stock_analysis 
-			WHERE stock_id = $1 
-			ORDER BY analysis_date DESC 
-			LIMIT $2
-		)`
 
-	_, err := r.db.Exec(query, stockID, keepCount)
-	return err
+// stockFilterWhereClause builds the WHERE clause selecting the stocks that
+// match filters, aliased as s. bind turns a value into the SQL that refers to
+// it, either a placeholder or a quoted literal.
+func stockFilterWhereClause(filters models.StockFilterParams, bind func(value any) string) string {
+	whereConditions := []string{}
+
+	// Filter by brokerage
+	if filters.Brokerage != "" && filters.Brokerage != "all" {
+		whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND LOWER(sa.brokerage) LIKE LOWER(%s))", bind("%"+filters.Brokerage+"%")))
+	}
+
+	// Filter by action type
+	if filters.ActionType != "" && filters.ActionType != "all" && actions.Valid(filters.ActionType) {
+		whereConditions = append(whereConditions, fmt.Sprintf("EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND sa.action_type = %s)", bind(filters.ActionType)))
+	}
+
+	// Filter by numeric price target
+	if filters.MinTarget != nil || filters.MaxTarget != nil {
+		targetConditions := []string{"sa.target_to_value IS NOT NULL"}
+		if filters.MinTarget != nil {
+			targetConditions = append(targetConditions, "sa.target_to_value >= "+bind(*filters.MinTarget))
+		}
+		if filters.MaxTarget != nil {
+			targetConditions = append(targetConditions, "sa.target_to_value <= "+bind(*filters.MaxTarget))
+		}
+		whereConditions = append(whereConditions, "EXISTS (SELECT 1 FROM stock_analysis sa WHERE sa.stock_id = s.id AND "+strings.Join(targetConditions, " AND ")+")")
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + fmt.Sprintf("(%s)", whereConditions[0])
+		for i := 1; i < len(whereConditions); i++ {
+			whereClause += " AND " + fmt.Sprintf("(%s)", whereConditions[i])
+		}
+	}
+
+	return whereClause
 }
 
 func (r *StockRepository) GetFilterOptions() (*models.FilterOptions, error) {
-	// Get distinct action types
-	actionQuery := `SELECT DISTINCT action FROM stock_analysis WHERE action IS NOT NULL AND action != '' ORDER BY action`
+	// Get the action types present, in display order
+	actionQuery := `SELECT DISTINCT action_type FROM stock_analysis WHERE action_type IS NOT NULL`
 	actionRows, err := r.db.Query(actionQuery)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get distinct actions: %w", err)
+		return nil, fmt.Errorf("failed to get distinct action types: %w", err)
 	}
 	defer actionRows.Close()
 
-	var actionTypes []models.FilterOption
-	actionTypes = append(actionTypes, models.FilterOption{Label: "All actions", Value: "all"})
-
+	present := make(map[string]bool)
 	for actionRows.Next() {
-		var action string
-		if err := actionRows.Scan(&action); err != nil {
+		var actionType string
+		if err := actionRows.Scan(&actionType); err != nil {
 			continue
 		}
+		present[actionType] = true
+	}
 
-		// Create label based on action type
-		label := formatActionLabel(action)
-		value := normalizeActionValue(action)
-
-		// Check if we already have this normalized value
-		exists := false
-		for _, existing := range actionTypes {
-			if existing.Value == value {
-				exists = true
-				break
-			}
-		}
-
-		if !exists {
-			actionTypes = append(actionTypes, models.FilterOption{
-				Label: label,
-				Value: value,
-			})
+	var actionTypes []models.FilterOption
+	actionTypes = append(actionTypes, models.FilterOption{Label: "All actions", Value: "all"})
+	for _, actionType := range actions.All {
+		if present[actionType] {
+			actionTypes = append(actionTypes, models.FilterOption{Label: actions.Label(actionType), Value: actionType})
 		}
 	}
 
@@ -462,53 +463,6 @@ func (r *StockRepository) GetFilterOptions() (*models.FilterOptions, error) {
 	}, nil
 }
 
-// Helper function to format action labels
-func formatActionLabel(action string) string {
-	action = strings.ToLower(action)
-	if strings.Contains(action, "initiated") {
-		return "Initiated"
-	} else if strings.Contains(action, "raised") {
-		return "Target Raised"
-	} else if strings.Contains(action, "lowered") {
-		return "Target Lowered"
-	} else if strings.Contains(action, "upgraded") {
-		return "Upgraded"
-	} else if strings.Contains(action, "downgraded") {
-		return "Downgraded"
-	} else if strings.Contains(action, "reiterated") {
-		return "Reiterated"
-	} else if strings.Contains(action, "target set") {
-		return "Target Set"
-	}
-	// Capitalize first letter of each word
-	words := strings.Fields(action)
-	for i, word := range words {
-		words[i] = strings.Title(word)
-	}
-	return strings.Join(words, " ")
-}
-
-// Helper function to normalize action values for filtering
-func normalizeActionValue(action string) string {
-	action = strings.ToLower(action)
-	if strings.Contains(action, "initiated") {
-		return "initiated"
-	} else if strings.Contains(action, "raised") {
-		return "raised"
-	} else if strings.Contains(action, "lowered") {
-		return "lowered"
-	} else if strings.Contains(action, "upgraded") {
-		return "upgraded"
-	} else if strings.Contains(action, "downgraded") {
-		return "downgraded"
-	} else if strings.Contains(action, "reiterated") {
-		return "reiterated"
-	} else if strings.Contains(action, "target set") {
-		return "target-set"
-	}
-	return strings.ReplaceAll(strings.ToLower(action), " ", "-")
-}
-
 func (r *StockRepository) GetMarketIntelligenceOverview() (*models.MarketIntelligenceOverview, error) {
 	overview := &models.MarketIntelligenceOverview{}
 
@@ -535,8 +489,8 @@ func (r *StockRepository) GetMarketIntelligenceOverview() (*models.MarketIntelli
 	upgradeQuery := fmt.Sprintf(`
 		SELECT COUNT(*) FROM stock_analysis 
 		WHERE created_at >= %s 
-		AND (LOWER(action) LIKE '%%raised%%' OR LOWER(action) LIKE '%%upgrade%%' OR LOWER(action) LIKE '%%initiated%%' 
-		     OR LOWER(rating_to) LIKE '%%buy%%' OR LOWER(rating_to) LIKE '%%outperform%%')`, thirtyDaysAgo)
+		AND (target_to_value > target_from_value OR action_type IN ('upgraded', 'initiated')
+		     OR rating_to_canonical >= 4)`, thirtyDaysAgo)
 	err = r.db.QueryRow(upgradeQuery).Scan(&upgrades)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get upgrades: %w", err)
@@ -546,14 +500,28 @@ func (r *StockRepository) GetMarketIntelligenceOverview() (*models.MarketIntelli
 	downgradeQuery := fmt.Sprintf(`
 		SELECT COUNT(*) FROM stock_analysis 
 		WHERE created_at >= %s 
-		AND (LOWER(action) LIKE '%%lowered%%' OR LOWER(action) LIKE '%%downgrade%%' 
-		     OR LOWER(rating_to) LIKE '%%sell%%' OR LOWER(rating_to) LIKE '%%underperform%%')`, thirtyDaysAgo)
+		AND (target_to_value < target_from_value OR action_type = 'downgraded'
+		     OR rating_to_canonical <= 2)`, thirtyDaysAgo)
 	err = r.db.QueryRow(downgradeQuery).Scan(&downgrades)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get downgrades: %w", err)
 	}
 	overview.Downgrades = downgrades
 
+	// Price target moves (last 30 days), from the numeric targets
+	targetQuery := fmt.Sprintf(`
+		SELECT
+			COALESCE(SUM(CASE WHEN target_to_value > target_from_value THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN target_to_value < target_from_value THEN 1 ELSE 0 END), 0),
+			COALESCE(AVG((target_to_value - target_from_value) / target_from_value * 100), 0)
+		FROM stock_analysis
+		WHERE created_at >= %s
+		AND target_parse_status = 'ok' AND target_from_value > 0 AND target_to_value > 0`, thirtyDaysAgo)
+	err = r.db.QueryRow(targetQuery).Scan(&overview.TargetsRaised, &overview.TargetsLowered, &overview.AverageTargetChange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price target changes: %w", err)
+	}
+
 	// Get top brokerages (last 30 days)
 	brokerageQuery := fmt.Sprintf(`
 		SELECT brokerage, COUNT(*) as analysis_count
@@ -591,21 +559,10 @@ func (r *StockRepository) GetMarketIntelligenceOverview() (*models.MarketIntelli
 
 	// Get top action types (last 30 days)
 	actionQuery := fmt.Sprintf(`
-		SELECT 
-			CASE 
-				WHEN LOWER(action) LIKE '%%initiated%%' THEN 'Initiated'
-				WHEN LOWER(action) LIKE '%%raised%%' THEN 'Target Raised'
-				WHEN LOWER(action) LIKE '%%lowered%%' THEN 'Target Lowered'
-				WHEN LOWER(action) LIKE '%%upgraded%%' THEN 'Upgraded'
-				WHEN LOWER(action) LIKE '%%downgraded%%' THEN 'Downgraded'
-				WHEN LOWER(action) LIKE '%%reiterated%%' THEN 'Reiterated'
-				WHEN LOWER(action) LIKE '%%target set%%' THEN 'Target Set'
-				ELSE 'Other'
-			END as action_type,
-			COUNT(*) as count
+		SELECT COALESCE(action_type, 'other') as action_type, COUNT(*) as count
 		FROM stock_analysis 
 		WHERE created_at >= %s AND action IS NOT NULL AND action != ''
-		GROUP BY action_type 
+		GROUP BY 1
 		ORDER BY count DESC 
 		LIMIT 5`, thirtyDaysAgo)
 
@@ -623,6 +580,7 @@ func (r *StockRepository) GetMarketIntelligenceOverview() (*models.MarketIntelli
 		if err != nil {
 			continue
 		}
+		actionType.ActionType = actions.Label(actionType.ActionType)
 		topActionTypes = append(topActionTypes, actionType)
 		totalActionAnalysis += actionType.Count
 	}
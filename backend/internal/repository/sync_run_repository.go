@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"stock-api/internal/models"
+)
+
+type SyncRunRepository struct {
+	db *sql.DB
+}
+
+func NewSyncRunRepository(db *sql.DB) *SyncRunRepository {
+	return &SyncRunRepository{db: db}
+}
+
+const syncRunColumns = `
+	id, process_name, status, resumed, COALESCE(resumed_from, ''), started_at, finished_at,
+	pages_fetched, stocks_created, stocks_updated, analyses_inserted, analyses_updated,
+	analyses_unchanged, analyses_failed, COALESCE(error_message, '')`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSyncRun(row rowScanner) (*models.SyncRun, error) {
+	run := &models.SyncRun{}
+	err := row.Scan(
+		&run.ID, &run.ProcessName, &run.Status, &run.Resumed, &run.ResumedFrom, &run.StartedAt, &run.FinishedAt,
+		&run.PagesFetched, &run.StocksCreated, &run.StocksUpdated, &run.AnalysesInserted, &run.AnalysesUpdated,
+		&run.AnalysesUnchanged, &run.AnalysesFailed, &run.ErrorMessage,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (r *SyncRunRepository) CreateSyncRun(run *models.SyncRun) error {
+	query := `
+		INSERT INTO sync_runs (process_name, status, resumed, resumed_from)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		RETURNING id, started_at`
+
+	if run.Status == "" {
+		run.Status = models.SyncRunStatusRunning
+	}
+
+	err := r.db.QueryRow(query, run.ProcessName, run.Status, run.Resumed, run.ResumedFrom).
+		Scan(&run.ID, &run.StartedAt)
+	if err != nil {
+		return fmt.Errorf("error creating sync run: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSyncRunProgress persists the counters of a run that is still in
+// progress so a crashed run keeps a record of how far it got.
+func (r *SyncRunRepository) UpdateSyncRunProgress(run *models.SyncRun) error {
+	query := `
+		UPDATE sync_runs
+		SET pages_fetched = $2, stocks_created = $3, stocks_updated = $4,
+			analyses_inserted = $5, analyses_updated = $6, analyses_unchanged = $7, analyses_failed = $8
+		WHERE id = $1`
+
+	_, err := r.db.Exec(query, run.ID, run.PagesFetched, run.StocksCreated, run.StocksUpdated,
+		run.AnalysesInserted, run.AnalysesUpdated, run.AnalysesUnchanged, run.AnalysesFailed)
+	return err
+}
+
+func (r *SyncRunRepository) FinishSyncRun(run *models.SyncRun) error {
+	if err := r.UpdateSyncRunProgress(run); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE sync_runs
+		SET status = $2, error_message = NULLIF($3, ''), finished_at = NOW()
+		WHERE id = $1
+		RETURNING finished_at`
+
+	return r.db.QueryRow(query, run.ID, run.Status, run.ErrorMessage).Scan(&run.FinishedAt)
+}
+
+// AbandonRunningSyncRuns closes runs left in the running state by a holder
+// that died without finishing them.
+func (r *SyncRunRepository) AbandonRunningSyncRuns(processName, message string) error {
+	query := `
+		UPDATE sync_runs
+		SET status = $3, error_message = $4, finished_at = NOW()
+		WHERE process_name = $1 AND status = $2`
+
+	_, err := r.db.Exec(query, processName, models.SyncRunStatusRunning, models.SyncRunStatusAbandoned, message)
+	return err
+}
+
+func (r *SyncRunRepository) GetSyncRunByID(id int) (*models.SyncRun, error) {
+	query := `SELECT ` + syncRunColumns + ` FROM sync_runs WHERE id = $1`
+
+	run, err := scanSyncRun(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return run, err
+}
+
+// GetLastFullSyncRun returns the most recent run that completed from the
+// first page, which is the best estimate of how many pages a sync takes.
+func (r *SyncRunRepository) GetLastFullSyncRun(processName string) (*models.SyncRun, error) {
+	query := `SELECT ` + syncRunColumns + `
+		FROM sync_runs
+		WHERE process_name = $1 AND status = $2 AND resumed = false
+		ORDER BY started_at DESC
+		LIMIT 1`
+
+	run, err := scanSyncRun(r.db.QueryRow(query, processName, models.SyncRunStatusCompleted))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return run, err
+}
+
+func (r *SyncRunRepository) GetSyncRunsPaginated(page, pageSize int, status string) (*models.PaginatedResponse[models.SyncRun], error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	whereClause := ""
+	queryArgs := []any{}
+	if status != "" {
+		whereClause = "WHERE status = $1"
+		queryArgs = append(queryArgs, status)
+	}
+
+	var totalCount int
+	countQuery := "SELECT COUNT(*) FROM sync_runs " + whereClause
+	if err := r.db.QueryRow(countQuery, queryArgs...).Scan(&totalCount); err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
+
+	query := fmt.Sprintf(`SELECT %s FROM sync_runs %s ORDER BY started_at DESC, id DESC LIMIT $%d OFFSET $%d`,
+		syncRunColumns, whereClause, len(queryArgs)+1, len(queryArgs)+2)
+	queryArgs = append(queryArgs, pageSize, offset)
+
+	rows, err := r.db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.SyncRun{}
+	for rows.Next() {
+		run, err := scanSyncRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		runs = append(runs, *run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	meta := models.PaginationMeta{
+		Page:        page,
+		PageSize:    pageSize,
+		TotalItems:  totalCount,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	return &models.PaginatedResponse[models.SyncRun]{
+		Data: runs,
+		Meta: meta,
+	}, nil
+}
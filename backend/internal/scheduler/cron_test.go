@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@often",
+		// Valid fields that never match together
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, 7, 2, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 7, 2, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 7, 2, 10, 15, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2025, 7, 3, 3, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 7, 2, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2025, 7, 3, 9, 30, 0, 0, time.UTC)},
+		// Sunday as 7
+		{"0 0 * * 7", time.Date(2025, 7, 6, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 15th or a Monday, whichever comes first
+		{"0 0 15 * 1", time.Date(2025, 7, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: Next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNextIsStrictlyAfter(t *testing.T) {
+	schedule, err := ParseSchedule("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	onTheHour := time.Date(2025, 7, 2, 10, 0, 0, 0, time.UTC)
+	if got, want := schedule.Next(onTheHour), onTheHour.Add(time.Hour); !got.Equal(want) {
+		t.Fatalf("Next = %v, want %v", got, want)
+	}
+
+	local := onTheHour.In(time.FixedZone("CEST", 2*60*60))
+	if got, want := schedule.Next(local), onTheHour.Add(time.Hour); !got.Equal(want) {
+		t.Fatalf("Next from a local time = %v, want %v", got, want)
+	}
+}
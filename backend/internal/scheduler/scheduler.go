@@ -0,0 +1,297 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"stock-api/internal/models"
+	"stock-api/internal/repository"
+)
+
+var (
+	ErrUnknownJob       = errors.New("unknown scheduler job")
+	ErrInvalidJobUpdate = errors.New("invalid scheduler job update")
+)
+
+// JobFunc runs a scheduled process. Jobs take their own process lock, so
+// when several replicas run the scheduler only one of them executes a job.
+type JobFunc func(ctx context.Context) error
+
+type job struct {
+	fn           JobFunc
+	running      bool
+	lastRunAt    *time.Time
+	lastResult   string
+	lastError    string
+	lastDuration time.Duration
+}
+
+// Scheduler triggers registered processes when the interval or cron schedule
+// stored in process_control says they are due.
+type Scheduler struct {
+	processRepo  *repository.ProcessControlRepository
+	enabled      bool
+	tickInterval time.Duration
+	instanceID   string
+	// startedAt stands in for last_execution of processes that never ran,
+	// so a fresh deployment waits a full interval before its first run
+	startedAt time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*job
+	wg   sync.WaitGroup
+}
+
+func New(processRepo *repository.ProcessControlRepository, enabled bool, tickInterval time.Duration, instanceID string) *Scheduler {
+	return &Scheduler{
+		processRepo:  processRepo,
+		enabled:      enabled,
+		tickInterval: tickInterval,
+		instanceID:   instanceID,
+		startedAt:    time.Now(),
+		jobs:         make(map[string]*job),
+	}
+}
+
+// Register adds a job for the process_control row named processName.
+func (s *Scheduler) Register(processName string, fn JobFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[processName] = &job{fn: fn}
+}
+
+// Start runs the scheduler loop until ctx is cancelled. It does nothing when
+// the scheduler is disabled.
+func (s *Scheduler) Start(ctx context.Context) {
+	if !s.enabled {
+		fmt.Println("Scheduler disabled, processes only run when triggered manually")
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.tickInterval)
+		defer ticker.Stop()
+
+		for {
+			s.tick(ctx, time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the loop and any running jobs have returned.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	for _, name := range s.jobNames() {
+		process, err := s.processRepo.GetProcessControl(name)
+		if err != nil {
+			fmt.Printf("Warning: scheduler failed to read process %s: %v\n", name, err)
+			continue
+		}
+		if process == nil || !process.Enabled {
+			continue
+		}
+		if process.IsRunning && !process.LockStale {
+			continue
+		}
+
+		nextRun, err := nextRunAt(process, s.startedAt)
+		if err != nil {
+			fmt.Printf("Warning: scheduler skipping process %s: %v\n", name, err)
+			continue
+		}
+		if now.Before(nextRun) {
+			continue
+		}
+
+		s.run(ctx, name)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, name string) {
+	s.mu.Lock()
+	j := s.jobs[name]
+	if j.running {
+		s.mu.Unlock()
+		return
+	}
+	j.running = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		fmt.Printf("Scheduler starting process %s\n", name)
+		start := time.Now()
+		err := j.fn(ctx)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		j.running = false
+		j.lastRunAt = &start
+		j.lastDuration = time.Since(start)
+		j.lastError = ""
+
+		switch {
+		case errors.Is(err, repository.ErrProcessLocked):
+			// Another replica got the lock first
+			j.lastResult = "skipped"
+		case err != nil:
+			j.lastResult = "failed"
+			j.lastError = err.Error()
+			fmt.Printf("Warning: scheduled process %s failed: %v\n", name, err)
+		default:
+			j.lastResult = "completed"
+		}
+	}()
+}
+
+func (s *Scheduler) jobNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nextRunAt returns when a process is next due. A process that never ran
+// is scheduled from since instead of its last execution.
+func nextRunAt(process *models.ProcessControl, since time.Time) (time.Time, error) {
+	if process.LastExecution != nil {
+		since = *process.LastExecution
+	}
+
+	if process.Schedule != "" {
+		schedule, err := ParseSchedule(process.Schedule)
+		if err != nil {
+			return time.Time{}, err
+		}
+		next := schedule.Next(since)
+		if next.IsZero() {
+			// Never due, rather than due on every tick
+			return time.Time{}, fmt.Errorf("schedule %q never matches", process.Schedule)
+		}
+		return next, nil
+	}
+
+	return since.Add(time.Duration(process.IntervalMinutes) * time.Minute), nil
+}
+
+// Status reports every process in process_control along with the scheduler
+// state this instance keeps for registered jobs.
+func (s *Scheduler) Status() (*models.SchedulerStatus, error) {
+	processes, err := s.processRepo.ListProcessControls()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.SchedulerStatus{
+		Enabled:     s.enabled,
+		InstanceID:  s.instanceID,
+		TickSeconds: int(s.tickInterval.Seconds()),
+		Jobs:        []models.SchedulerJobStatus{},
+	}
+
+	for i := range processes {
+		status.Jobs = append(status.Jobs, s.jobStatus(&processes[i]))
+	}
+
+	return status, nil
+}
+
+func (s *Scheduler) jobStatus(process *models.ProcessControl) models.SchedulerJobStatus {
+	jobStatus := models.SchedulerJobStatus{
+		Name:            process.ProcessName,
+		Enabled:         process.Enabled,
+		Schedule:        process.Schedule,
+		IntervalMinutes: process.IntervalMinutes,
+		LastExecution:   process.LastExecution,
+		IsRunning:       process.IsRunning && !process.LockStale,
+		LockOwner:       process.OwnerID,
+	}
+
+	if nextRun, err := nextRunAt(process, s.startedAt); err == nil {
+		jobStatus.NextRunAt = &nextRun
+	} else {
+		jobStatus.LastError = err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if j, ok := s.jobs[process.ProcessName]; ok {
+		jobStatus.Registered = true
+		jobStatus.RunningHere = j.running
+		jobStatus.LastRunAt = j.lastRunAt
+		jobStatus.LastResult = j.lastResult
+		if j.lastError != "" {
+			jobStatus.LastError = j.lastError
+		}
+		jobStatus.LastDurationMs = j.lastDuration.Milliseconds()
+	}
+
+	return jobStatus
+}
+
+// UpdateJob changes the enabled flag, cron schedule or interval of a process.
+// The settings live in process_control, so they apply to every replica.
+func (s *Scheduler) UpdateJob(processName string, update models.SchedulerJobUpdate) (*models.SchedulerJobStatus, error) {
+	process, err := s.processRepo.GetProcessControl(processName)
+	if err != nil {
+		return nil, err
+	}
+	if process == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJob, processName)
+	}
+
+	if update.Enabled != nil {
+		process.Enabled = *update.Enabled
+	}
+	if update.Schedule != nil {
+		if *update.Schedule != "" {
+			if _, err := ParseSchedule(*update.Schedule); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidJobUpdate, err)
+			}
+		}
+		process.Schedule = *update.Schedule
+	}
+	if update.IntervalMinutes != nil {
+		if *update.IntervalMinutes < 1 {
+			return nil, fmt.Errorf("%w: interval_minutes must be at least 1", ErrInvalidJobUpdate)
+		}
+		process.IntervalMinutes = *update.IntervalMinutes
+	}
+
+	found, err := s.processRepo.UpdateProcessSchedule(processName, process.Enabled, process.Schedule, process.IntervalMinutes)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJob, processName)
+	}
+
+	jobStatus := s.jobStatus(process)
+	return &jobStatus, nil
+}
@@ -0,0 +1,44 @@
+package scoring
+
+import (
+	"math"
+
+	"stock-api/internal/models"
+)
+
+// Weight derives a brokerage's credibility weight from its track record.
+// Brokerages without stats weigh 1.
+func (c CredibilityRules) Weight(stats models.BrokerageStats) float64 {
+	weight := 1.0
+
+	// More coverage earns more of the bonus, with diminishing returns
+	count := float64(stats.AnalysesCount)
+	weight += c.CoverageBonus * count / (count + float64(c.CoverageHalfCount))
+
+	// A hit rate above 50% raises the weight, one below lowers it
+	if stats.HitRate != nil && stats.EvaluatedCalls >= c.MinEvaluatedCalls {
+		hitRate := *stats.HitRate
+		weight += c.HitRateImpact * (2*hitRate - 1)
+	}
+
+	weight = math.Max(c.MinWeight, math.Min(c.MaxWeight, weight))
+	return math.Round(weight*1000) / 1000
+}
+
+// credibilityWeights derives the weight of every brokerage in stats.
+func credibilityWeights(rules CredibilityRules, stats []models.BrokerageStats) map[string]float64 {
+	weights := make(map[string]float64, len(stats))
+	for _, stat := range stats {
+		weights[stat.Brokerage] = rules.Weight(stat)
+	}
+	return weights
+}
+
+// Credibility returns the credibility weight of a brokerage, 1 for those
+// without stats.
+func (s *Scope) Credibility(brokerage string) float64 {
+	if weight, ok := s.BrokerageWeights[brokerage]; ok {
+		return weight
+	}
+	return 1
+}
@@ -0,0 +1,67 @@
+package scoring
+
+import (
+	"math"
+	"time"
+
+	"stock-api/internal/models"
+)
+
+// Since returns the oldest analysis date inside the window at now.
+func (d DecayRules) Since(now time.Time) time.Time {
+	return now.AddDate(0, 0, -d.WindowDays)
+}
+
+// Weight returns how much an analysis of the given age counts, from 1 for
+// one made now down to 0 outside the window. Analyses dated in the future
+// count fully.
+func (d DecayRules) Weight(age time.Duration) float64 {
+	if age < 0 {
+		age = 0
+	}
+	days := age.Hours() / 24
+	if days > float64(d.WindowDays) {
+		return 0
+	}
+	return math.Pow(0.5, days/d.HalfLifeDays)
+}
+
+// Weight returns how much an analysis counts at the time of scoring: its
+// decay weight scaled by the credibility of its brokerage.
+func (s *Scope) Weight(analysis models.StockAnalysis) float64 {
+	return s.Decay(analysis) * s.Credibility(analysis.Brokerage)
+}
+
+// Decay returns the decay weight of an analysis at the time of scoring.
+func (s *Scope) Decay(analysis models.StockAnalysis) float64 {
+	return s.Profile.Decay.Weight(s.Now.Sub(analysis.AnalysisDate))
+}
+
+// decayedPoints combines the points of the analyses a factor could score.
+// Each analysis' points are scaled by its brokerage's credibility and
+// averaged by decay weight. The average divides by no less than 1, so
+// several fresh upgrades score like one while points from stale analyses
+// alone fade away. Since only the decay weight normalizes, credibility
+// works both ways: a lone fresh analysis of a 1.5-weight brokerage scores
+// 1.5 times its points, one of a 0.5-weight brokerage half of them. It
+// reports false when no analysis in the window was scored.
+func decayedPoints(stock models.StockWithAnalysis, scope *Scope, points func(models.StockAnalysis) (float64, bool)) (float64, bool) {
+	total, decays := 0.0, 0.0
+	for _, analysis := range stock.LatestAnalysis {
+		decay := scope.Decay(analysis)
+		if decay == 0 {
+			continue
+		}
+		value, ok := points(analysis)
+		if !ok {
+			continue
+		}
+		total += decay * scope.Credibility(analysis.Brokerage) * value
+		decays += decay
+	}
+
+	if decays == 0 {
+		return 0, false
+	}
+	return total / math.Max(decays, 1), true
+}
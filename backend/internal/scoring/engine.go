@@ -0,0 +1,222 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"stock-api/internal/actions"
+	"stock-api/internal/models"
+	"stock-api/internal/ratings"
+)
+
+// Engine scores stocks with the factors of each strategy, weighted by the
+// active profile and the credibility of each brokerage.
+type Engine struct {
+	strategies []Strategy
+	profile    atomic.Pointer[Profile]
+	weights    atomic.Pointer[map[string]float64]
+
+	// mu serializes updates of the profile and brokerage stats, which both
+	// change the weights
+	mu         sync.Mutex
+	brokerages []models.BrokerageStats
+}
+
+func NewEngine(strategies []Strategy, profile *Profile) *Engine {
+	engine := &Engine{strategies: strategies}
+	engine.SetProfile(profile)
+	return engine
+}
+
+// Profile returns the active scoring profile.
+func (e *Engine) Profile() *Profile {
+	return e.profile.Load()
+}
+
+// SetProfile makes profile the active one and rederives the brokerage
+// weights with its rules. Scores already being computed finish with the
+// profile they started with.
+func (e *Engine) SetProfile(profile *Profile) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	weights := credibilityWeights(profile.Credibility, e.brokerages)
+	e.profile.Store(profile)
+	e.weights.Store(&weights)
+}
+
+// SetBrokerageStats replaces the track records brokerage weights are
+// derived from.
+func (e *Engine) SetBrokerageStats(stats []models.BrokerageStats) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	weights := credibilityWeights(e.profile.Load().Credibility, stats)
+	e.brokerages = stats
+	e.weights.Store(&weights)
+}
+
+// Strategies returns the strategies in the order they were given.
+func (e *Engine) Strategies() []Strategy {
+	return append([]Strategy(nil), e.strategies...)
+}
+
+// Strategy returns the strategy with the given name.
+func (e *Engine) Strategy(name string) (Strategy, bool) {
+	for _, strategy := range e.strategies {
+		if strategy.Name == name {
+			return strategy, true
+		}
+	}
+	return Strategy{}, false
+}
+
+// scope returns the scope of a score computed now with the active profile
+// and brokerage weights.
+func (e *Engine) scope() *Scope {
+	return &Scope{Profile: e.profile.Load(), Now: time.Now(), BrokerageWeights: *e.weights.Load()}
+}
+
+// ScoreAll scores a stock with every strategy, using the same profile and
+// time of scoring for all of them.
+func (e *Engine) ScoreAll(stock models.StockWithAnalysis) []models.RecommendationScore {
+	scope := e.scope()
+	scores := make([]models.RecommendationScore, 0, len(e.strategies))
+	for _, strategy := range e.strategies {
+		scores = append(scores, scoreStock(strategy, scope, stock))
+	}
+	return scores
+}
+
+// Score scores a stock with one strategy.
+func (e *Engine) Score(strategy Strategy, stock models.StockWithAnalysis) models.RecommendationScore {
+	return scoreStock(strategy, e.scope(), stock)
+}
+
+// scoreStock runs every factor of a strategy on a stock and returns its
+// score with the per-factor breakdown, confidence, reason and profile
+// version.
+func scoreStock(strategy Strategy, scope *Scope, stock models.StockWithAnalysis) models.RecommendationScore {
+	profile := scope.Profile
+	score := models.RecommendationScore{
+		StockID:        stock.ID,
+		Strategy:       strategy.Name,
+		TotalScore:     profile.BaseScore,
+		Breakdown:      []models.FactorScore{},
+		ProfileVersion: profile.Version,
+	}
+
+	for _, factor := range strategy.Factors.Factors() {
+		result := factor.Score(stock, scope)
+		// Decay weights make points fractional; keep two decimals
+		result.Score = math.Round(result.Score*100) / 100
+		score.TotalScore += result.Score
+		score.Breakdown = append(score.Breakdown, models.FactorScore{
+			Factor:      factor.Name(),
+			Score:       result.Score,
+			Explanation: result.Explanation,
+		})
+		setLegacyColumn(&score, factor.Name(), result.Score)
+	}
+
+	// Get latest analysis ID if available
+	if len(stock.LatestAnalysis) > 0 {
+		score.LatestAnalysisID = &stock.LatestAnalysis[0].ID
+	}
+
+	score.Confidence = profile.ConfidenceOf(score.TotalScore)
+	score.Reason = generateReason(stock, profile)
+	return score
+}
+
+// setLegacyColumn keeps the per-factor columns of recommendation_scores,
+// which predate the stored breakdown, filled for the built-in factors.
+func setLegacyColumn(score *models.RecommendationScore, factor string, value float64) {
+	switch factor {
+	case FactorRating:
+		score.RatingScore = value
+	case FactorRatingChange:
+		score.RatingChangeScore = value
+	case FactorTargetChange:
+		score.TargetChangeScore = value
+	case FactorAction:
+		score.ActionScore = value
+	case FactorCoverage:
+		score.CoverageScore = value
+	}
+}
+
+// AnalyzeStocks scores stocks with analyst coverage using strategy and
+// returns the top ten.
+func (e *Engine) AnalyzeStocks(strategy Strategy, stocks []models.StockWithAnalysis) []models.StockRecommendation {
+	var recommendations []models.StockRecommendation
+
+	for _, stock := range stocks {
+		if len(stock.LatestAnalysis) == 0 {
+			continue
+		}
+
+		score := e.Score(strategy, stock)
+		recommendations = append(recommendations, models.StockRecommendation{
+			Stock:          stock,
+			Score:          score.TotalScore,
+			Reason:         score.Reason,
+			Confidence:     score.Confidence,
+			Breakdown:      score.Breakdown,
+			Strategy:       score.Strategy,
+			ProfileVersion: score.ProfileVersion,
+		})
+	}
+
+	sort.Slice(recommendations, func(i, j int) bool {
+		return recommendations[i].Score > recommendations[j].Score
+	})
+
+	if len(recommendations) > 10 {
+		recommendations = recommendations[:10]
+	}
+
+	return recommendations
+}
+
+func generateReason(stock models.StockWithAnalysis, profile *Profile) string {
+	if len(stock.LatestAnalysis) == 0 {
+		return "No recent analyst coverage"
+	}
+
+	latestAnalysis := stock.LatestAnalysis[0]
+	reasons := []string{}
+
+	// Check rating
+	if rating := latestAnalysis.RatingToCanonical; rating != nil && *rating >= ratings.Buy {
+		reasons = append(reasons, ratings.Label(*rating)+" rating from "+latestAnalysis.Brokerage)
+	}
+
+	// Check price target
+	if targetChange, ok := TargetChange(latestAnalysis); ok {
+		if targetChange > profile.TargetChange.LargeChange {
+			reasons = append(reasons, fmt.Sprintf("Price target raised by %.1f%%", targetChange*100))
+		}
+	}
+
+	// Check action
+	if latestAnalysis.ActionType == actions.Initiated {
+		reasons = append(reasons, "New analyst coverage")
+	}
+
+	// Check multiple analyses
+	if len(stock.LatestAnalysis) >= 3 {
+		reasons = append(reasons, "Multiple recent analyst updates")
+	}
+
+	if len(reasons) == 0 {
+		return "Analyst coverage available from " + latestAnalysis.Brokerage
+	}
+
+	return strings.Join(reasons, ", ")
+}
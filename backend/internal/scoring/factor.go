@@ -0,0 +1,85 @@
+// Package scoring computes stock recommendation scores from a registry of
+// independent factors. Each factor contributes points on top of a neutral
+// base score and explains its contribution.
+package scoring
+
+import (
+	"fmt"
+	"time"
+
+	"stock-api/internal/models"
+)
+
+// Factor is one component of a recommendation score. Score receives a stock
+// with its newest analyses first and the scope of the score, and returns the
+// points it adds to (or subtracts from) the base score.
+type Factor interface {
+	Name() string
+	Score(stock models.StockWithAnalysis, scope *Scope) Result
+}
+
+// Scope is what a stock is scored against: the active profile, the time of
+// scoring, which analysis ages are measured from, and the credibility weight
+// of each brokerage with stats.
+type Scope struct {
+	Profile          *Profile
+	Now              time.Time
+	BrokerageWeights map[string]float64
+}
+
+// Result is a factor's contribution and a short human-readable explanation.
+type Result struct {
+	Score       float64
+	Explanation string
+}
+
+// Registry holds the factors of an engine in registration order, which is
+// the order of the stored breakdown.
+type Registry struct {
+	factors []Factor
+	names   map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+// Register adds a factor. Factor names must be unique since they key the
+// stored breakdown.
+func (r *Registry) Register(factor Factor) error {
+	if factor.Name() == "" {
+		return fmt.Errorf("factor name is required")
+	}
+	if r.names[factor.Name()] {
+		return fmt.Errorf("factor %q is already registered", factor.Name())
+	}
+	r.names[factor.Name()] = true
+	r.factors = append(r.factors, factor)
+	return nil
+}
+
+// MustRegister is Register for factor sets known at compile time.
+func (r *Registry) MustRegister(factors ...Factor) *Registry {
+	for _, factor := range factors {
+		if err := r.Register(factor); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Factors returns the registered factors in registration order.
+func (r *Registry) Factors() []Factor {
+	return append([]Factor(nil), r.factors...)
+}
+
+// DefaultRegistry returns the built-in factors.
+func DefaultRegistry() *Registry {
+	return NewRegistry().MustRegister(
+		RatingFactor{},
+		RatingChangeFactor{},
+		TargetChangeFactor{},
+		ActionFactor{},
+		CoverageFactor{},
+	)
+}
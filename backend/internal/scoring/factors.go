@@ -0,0 +1,269 @@
+package scoring
+
+import (
+	"fmt"
+
+	"stock-api/internal/actions"
+	"stock-api/internal/models"
+	"stock-api/internal/pricing"
+	"stock-api/internal/ratings"
+)
+
+// Names of the built-in factors. They also select the legacy per-factor
+// columns of recommendation_scores.
+const (
+	FactorRating       = "rating"
+	FactorRatingChange = "rating_change"
+	FactorTargetChange = "target_change"
+	FactorAction       = "action"
+	FactorCoverage     = "coverage"
+	FactorConsensus    = "consensus"
+	FactorNewCoverage  = "new_coverage"
+)
+
+// RatingFactor scores the canonical ratings of the analyses in the window,
+// weighted by their age and brokerage credibility.
+type RatingFactor struct{}
+
+func (RatingFactor) Name() string { return FactorRating }
+
+func (RatingFactor) Score(stock models.StockWithAnalysis, scope *Scope) Result {
+	if len(stock.LatestAnalysis) == 0 {
+		return Result{Explanation: "No analyst coverage"}
+	}
+
+	rated := 0
+	score, ok := decayedPoints(stock, scope, func(analysis models.StockAnalysis) (float64, bool) {
+		if analysis.RatingToCanonical == nil {
+			return 0, false
+		}
+		rated++
+		return scope.Profile.RatingPoints[*analysis.RatingToCanonical], true
+	})
+	if !ok {
+		return Result{Explanation: "No recent ratings mapped to the canonical scale"}
+	}
+
+	latest := stock.LatestAnalysis[0]
+	explanation := fmt.Sprintf("%d recent ratings, weighted by recency and credibility", rated)
+	if latest.RatingToCanonical != nil {
+		explanation = fmt.Sprintf("%s rating from %s, %s", ratings.Label(*latest.RatingToCanonical), latest.Brokerage, explanation)
+	}
+	return Result{Score: score, Explanation: explanation}
+}
+
+// RatingChangeFactor rewards upgrades and penalizes downgrades among the
+// analyses in the window, weighted by their age and brokerage credibility.
+// Only analyses with both ratings mapped are scored.
+type RatingChangeFactor struct{}
+
+func (RatingChangeFactor) Name() string { return FactorRatingChange }
+
+func (RatingChangeFactor) Score(stock models.StockWithAnalysis, scope *Scope) Result {
+	if len(stock.LatestAnalysis) == 0 {
+		return Result{Explanation: "No analyst coverage"}
+	}
+
+	rules := scope.Profile.RatingChange
+	upgrades, downgrades := 0, 0
+	score, ok := decayedPoints(stock, scope, func(analysis models.StockAnalysis) (float64, bool) {
+		if analysis.RatingToCanonical == nil || analysis.RatingFromCanonical == nil {
+			return 0, false
+		}
+		from, to := *analysis.RatingFromCanonical, *analysis.RatingToCanonical
+		switch {
+		case to > from:
+			upgrades++
+			return rules.Upgrade, true
+		case to < from:
+			downgrades++
+			return rules.Downgrade, true
+		}
+		return 0, true
+	})
+	if !ok {
+		return Result{Explanation: "No comparable recent ratings"}
+	}
+
+	return Result{
+		Score:       score,
+		Explanation: fmt.Sprintf("%d upgrades and %d downgrades, weighted by recency and credibility", upgrades, downgrades),
+	}
+}
+
+// TargetChangeFactor scores the price target moves of the analyses in the
+// window, weighted by their age and brokerage credibility.
+type TargetChangeFactor struct{}
+
+func (TargetChangeFactor) Name() string { return FactorTargetChange }
+
+func (TargetChangeFactor) Score(stock models.StockWithAnalysis, scope *Scope) Result {
+	if len(stock.LatestAnalysis) == 0 {
+		return Result{Explanation: "No analyst coverage"}
+	}
+
+	rules := scope.Profile.TargetChange
+	changes := 0
+	score, ok := decayedPoints(stock, scope, func(analysis models.StockAnalysis) (float64, bool) {
+		change, ok := TargetChange(analysis)
+		if !ok {
+			return 0, false
+		}
+		changes++
+		switch {
+		case change > rules.LargeChange:
+			return rules.LargeRaise, true
+		case change > rules.SmallChange:
+			return rules.SmallRaise, true
+		case change < -rules.LargeChange:
+			return rules.LargeCut, true
+		case change < -rules.SmallChange:
+			return rules.SmallCut, true
+		}
+		return 0, true
+	})
+	if !ok {
+		return Result{Explanation: "No comparable recent price targets"}
+	}
+
+	explanation := fmt.Sprintf("%d recent price target changes, weighted by recency and credibility", changes)
+	if change, ok := TargetChange(stock.LatestAnalysis[0]); ok {
+		explanation = fmt.Sprintf("Price target changed by %+.1f%%, %s", change*100, explanation)
+	}
+	return Result{Score: score, Explanation: explanation}
+}
+
+// ActionFactor scores the classified actions of the analyses in the window,
+// weighted by their age and brokerage credibility.
+type ActionFactor struct{}
+
+func (ActionFactor) Name() string { return FactorAction }
+
+func (ActionFactor) Score(stock models.StockWithAnalysis, scope *Scope) Result {
+	if len(stock.LatestAnalysis) == 0 {
+		return Result{Explanation: "No analyst coverage"}
+	}
+
+	score, ok := decayedPoints(stock, scope, func(analysis models.StockAnalysis) (float64, bool) {
+		return scope.Profile.ActionPoints[analysis.ActionType], true
+	})
+	if !ok {
+		return Result{Explanation: "No recent analyst actions"}
+	}
+
+	return Result{
+		Score:       score,
+		Explanation: "Latest action: " + actions.Label(stock.LatestAnalysis[0].ActionType) + ", recent actions weighted by recency and credibility",
+	}
+}
+
+// CoverageFactor rewards stocks with several recent analyses and with
+// consistently positive ratings. Analyses count by their weight, so three
+// fresh analyses from average brokerages meet a minimum of three but three
+// stale ones do not.
+type CoverageFactor struct{}
+
+func (CoverageFactor) Name() string { return FactorCoverage }
+
+func (CoverageFactor) Score(stock models.StockWithAnalysis, scope *Scope) Result {
+	rules := scope.Profile.Coverage
+	score := 0.0
+
+	analyses, positive := 0.0, 0.0
+	for _, analysis := range stock.LatestAnalysis {
+		weight := scope.Weight(analysis)
+		analyses += weight
+		if analysis.RatingToCanonical != nil && *analysis.RatingToCanonical >= rules.PositiveRating {
+			positive += weight
+		}
+	}
+
+	// Multiple recent analyses bonus
+	if analyses >= float64(rules.MinAnalyses) {
+		score += rules.AnalysesBonus
+	}
+
+	// Check for consistent positive sentiment
+	if positive >= float64(rules.MinPositive) {
+		score += rules.PositiveBonus
+	}
+
+	return Result{
+		Score: score,
+		Explanation: fmt.Sprintf("%.1f recent analyses, %.1f rated %s or better, weighted by recency and credibility",
+			analyses, positive, ratings.Label(rules.PositiveRating)),
+	}
+}
+
+// ConsensusFactor scores the average canonical rating of the analyses in the
+// window, weighted by their age and brokerage credibility, so one outlier
+// moves the score less than in RatingFactor. Unlike RatingFactor it does not
+// fade when all analyses are stale, since the consensus itself has not
+// changed.
+type ConsensusFactor struct{}
+
+func (ConsensusFactor) Name() string { return FactorConsensus }
+
+func (ConsensusFactor) Score(stock models.StockWithAnalysis, scope *Scope) Result {
+	points, ratingSum, weights, rated := 0.0, 0.0, 0.0, 0
+	for _, analysis := range stock.LatestAnalysis {
+		weight := scope.Weight(analysis)
+		if analysis.RatingToCanonical == nil || weight == 0 {
+			continue
+		}
+		points += weight * scope.Profile.RatingPoints[*analysis.RatingToCanonical]
+		ratingSum += weight * float64(*analysis.RatingToCanonical)
+		weights += weight
+		rated++
+	}
+
+	if rated == 0 {
+		return Result{Explanation: "No mapped ratings among the recent analyses"}
+	}
+
+	return Result{
+		Score:       points / weights,
+		Explanation: fmt.Sprintf("Average rating %.1f across %d analyses, weighted by recency and credibility", ratingSum/weights, rated),
+	}
+}
+
+// NewCoverageFactor scores every initiation in the window like ActionFactor
+// scores an initiation, weighted by its age and brokerage credibility.
+type NewCoverageFactor struct{}
+
+func (NewCoverageFactor) Name() string { return FactorNewCoverage }
+
+func (NewCoverageFactor) Score(stock models.StockWithAnalysis, scope *Scope) Result {
+	if len(stock.LatestAnalysis) == 0 {
+		return Result{Explanation: "No analyst coverage"}
+	}
+
+	initiations, weights := 0, 0.0
+	for _, analysis := range stock.LatestAnalysis {
+		if analysis.ActionType == actions.Initiated {
+			initiations++
+			weights += scope.Weight(analysis)
+		}
+	}
+
+	return Result{
+		Score:       weights * scope.Profile.ActionPoints[actions.Initiated],
+		Explanation: fmt.Sprintf("%d of the %d recent analyses initiate coverage", initiations, len(stock.LatestAnalysis)),
+	}
+}
+
+// TargetChange returns the relative move from the old to the new price
+// target, using the numeric targets parsed at ingestion. It reports false
+// when either target is missing, unparseable or not positive.
+func TargetChange(analysis models.StockAnalysis) (float64, bool) {
+	if analysis.TargetParseStatus != pricing.StatusOK || analysis.TargetFromValue == nil || analysis.TargetToValue == nil {
+		return 0, false
+	}
+
+	from, to := *analysis.TargetFromValue, *analysis.TargetToValue
+	if from <= 0 || to <= 0 {
+		return 0, false
+	}
+
+	return (to - from) / from, true
+}
@@ -0,0 +1,254 @@
+package scoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stock-api/internal/actions"
+	"stock-api/internal/ratings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidProfile is returned for a scoring profile that cannot be used.
+var ErrInvalidProfile = errors.New("invalid scoring profile")
+
+// DefaultProfileVersion is the version of the compiled-in profile used when
+// no profile file is configured.
+const DefaultProfileVersion = "builtin"
+
+// Profile holds every weight and threshold of the built-in factors. Its
+// version is stored with each score so a score can be traced back to the
+// rules that produced it.
+type Profile struct {
+	Version    string               `yaml:"version" json:"version"`
+	BaseScore  float64              `yaml:"base_score" json:"base_score"`
+	Confidence ConfidenceThresholds `yaml:"confidence" json:"confidence"`
+	// RatingPoints are added for the canonical ratings of the analyses in the
+	// decay window; unmapped ratings are not scored
+	RatingPoints map[int]float64   `yaml:"rating_points" json:"rating_points"`
+	RatingChange RatingChangeRules `yaml:"rating_change" json:"rating_change"`
+	TargetChange TargetChangeRules `yaml:"target_change" json:"target_change"`
+	// ActionPoints are added for the action types of the analyses in the
+	// decay window; action types not listed add nothing
+	ActionPoints map[string]float64 `yaml:"action_points" json:"action_points"`
+	Coverage     CoverageRules      `yaml:"coverage" json:"coverage"`
+	Decay        DecayRules         `yaml:"decay" json:"decay"`
+	Credibility  CredibilityRules   `yaml:"credibility" json:"credibility"`
+}
+
+// ConfidenceThresholds are the minimum total scores of High and Medium
+// confidence; lower scores are Low.
+type ConfidenceThresholds struct {
+	High   float64 `yaml:"high" json:"high"`
+	Medium float64 `yaml:"medium" json:"medium"`
+}
+
+type RatingChangeRules struct {
+	Upgrade   float64 `yaml:"upgrade" json:"upgrade"`
+	Downgrade float64 `yaml:"downgrade" json:"downgrade"`
+}
+
+// TargetChangeRules score a price target move by its size. Changes are
+// fractions, so 0.1 is 10%.
+type TargetChangeRules struct {
+	LargeChange float64 `yaml:"large_change" json:"large_change"`
+	SmallChange float64 `yaml:"small_change" json:"small_change"`
+	LargeRaise  float64 `yaml:"large_raise" json:"large_raise"`
+	SmallRaise  float64 `yaml:"small_raise" json:"small_raise"`
+	LargeCut    float64 `yaml:"large_cut" json:"large_cut"`
+	SmallCut    float64 `yaml:"small_cut" json:"small_cut"`
+}
+
+type CoverageRules struct {
+	// MinAnalyses recent analyses earn AnalysesBonus
+	MinAnalyses   int     `yaml:"min_analyses" json:"min_analyses"`
+	AnalysesBonus float64 `yaml:"analyses_bonus" json:"analyses_bonus"`
+	// MinPositive analyses rated PositiveRating or better earn PositiveBonus
+	MinPositive    int     `yaml:"min_positive" json:"min_positive"`
+	PositiveRating int     `yaml:"positive_rating" json:"positive_rating"`
+	PositiveBonus  float64 `yaml:"positive_bonus" json:"positive_bonus"`
+}
+
+// DecayRules weight each analysis by its age: an analysis HalfLifeDays old
+// counts half as much as one from today, and analyses older than WindowDays
+// are not scored at all.
+type DecayRules struct {
+	HalfLifeDays float64 `yaml:"half_life_days" json:"half_life_days"`
+	WindowDays   int     `yaml:"window_days" json:"window_days"`
+}
+
+// CredibilityRules turn a brokerage's track record into the weight its
+// analyses carry, on top of their decay weight. Brokerages start from 1,
+// earn up to CoverageBonus with coverage volume, and once they have
+// MinEvaluatedCalls move by up to HitRateImpact either way with their hit
+// rate. Weights stay between MinWeight and MaxWeight.
+type CredibilityRules struct {
+	MinWeight float64 `yaml:"min_weight" json:"min_weight"`
+	MaxWeight float64 `yaml:"max_weight" json:"max_weight"`
+	// CoverageHalfCount analyses earn half of CoverageBonus
+	CoverageBonus     float64 `yaml:"coverage_bonus" json:"coverage_bonus"`
+	CoverageHalfCount int     `yaml:"coverage_half_count" json:"coverage_half_count"`
+	// A 100% hit rate adds HitRateImpact, a 0% one subtracts it
+	HitRateImpact     float64 `yaml:"hit_rate_impact" json:"hit_rate_impact"`
+	MinEvaluatedCalls int     `yaml:"min_evaluated_calls" json:"min_evaluated_calls"`
+	// HorizonDays after a rating change its call is checked against the price
+	HorizonDays int `yaml:"horizon_days" json:"horizon_days"`
+}
+
+// DefaultProfile returns the compiled-in weights and thresholds.
+func DefaultProfile() *Profile {
+	return &Profile{
+		Version:    DefaultProfileVersion,
+		BaseScore:  50,
+		Confidence: ConfidenceThresholds{High: 75, Medium: 60},
+		RatingPoints: map[int]float64{
+			ratings.StrongBuy:  40,
+			ratings.Buy:        25,
+			ratings.Hold:       0,
+			ratings.Sell:       -20,
+			ratings.StrongSell: -40,
+		},
+		RatingChange: RatingChangeRules{Upgrade: 15, Downgrade: -10},
+		TargetChange: TargetChangeRules{
+			LargeChange: 0.1,
+			SmallChange: 0.05,
+			LargeRaise:  20,
+			SmallRaise:  10,
+			LargeCut:    -15,
+			SmallCut:    -8,
+		},
+		ActionPoints: map[string]float64{
+			actions.Initiated:     10,
+			actions.Upgraded:      12,
+			actions.TargetRaised:  12,
+			actions.Downgraded:    -8,
+			actions.TargetLowered: -8,
+			actions.Reiterated:    5,
+		},
+		Coverage: CoverageRules{
+			MinAnalyses:    3,
+			AnalysesBonus:  5,
+			MinPositive:    2,
+			PositiveRating: ratings.Buy,
+			PositiveBonus:  8,
+		},
+		Decay: DecayRules{HalfLifeDays: 30, WindowDays: 180},
+		Credibility: CredibilityRules{
+			MinWeight:         0.5,
+			MaxWeight:         1.5,
+			CoverageBonus:     0.2,
+			CoverageHalfCount: 50,
+			HitRateImpact:     0.3,
+			MinEvaluatedCalls: 10,
+			HorizonDays:       90,
+		},
+	}
+}
+
+// LoadProfile reads a YAML or JSON (by .json extension) scoring profile.
+// Settings the file leaves out keep their default, but the version is
+// required. An empty path returns the default profile.
+func LoadProfile(path string) (*Profile, error) {
+	if path == "" {
+		return DefaultProfile(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scoring profile: %w", err)
+	}
+
+	profile := DefaultProfile()
+	profile.Version = ""
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(profile)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(profile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidProfile, filepath.Base(path), err)
+	}
+
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Validate checks that the profile is complete and its thresholds are
+// ordered.
+func (p *Profile) Validate() error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidProfile, fmt.Sprintf(format, args...))
+	}
+
+	if strings.TrimSpace(p.Version) == "" {
+		return invalid("version is required")
+	}
+	if len(p.Version) > 50 {
+		return invalid("version must be at most 50 characters")
+	}
+	if p.Confidence.High <= p.Confidence.Medium {
+		return invalid("confidence.high must be above confidence.medium")
+	}
+	for value := range p.RatingPoints {
+		if !ratings.Valid(value) {
+			return invalid("rating_points has %d, which is not on the canonical scale", value)
+		}
+	}
+	for _, point := range ratings.Scale {
+		if _, ok := p.RatingPoints[point.Value]; !ok {
+			return invalid("rating_points is missing %d (%s)", point.Value, point.Label)
+		}
+	}
+	if p.TargetChange.SmallChange <= 0 || p.TargetChange.LargeChange <= p.TargetChange.SmallChange {
+		return invalid("target_change needs 0 < small_change < large_change")
+	}
+	for actionType := range p.ActionPoints {
+		if !actions.Valid(actionType) {
+			return invalid("action_points has unknown action type %q", actionType)
+		}
+	}
+	if p.Coverage.MinAnalyses < 1 || p.Coverage.MinPositive < 1 {
+		return invalid("coverage.min_analyses and coverage.min_positive must be at least 1")
+	}
+	if !ratings.Valid(p.Coverage.PositiveRating) {
+		return invalid("coverage.positive_rating must be between %d and %d", ratings.StrongSell, ratings.StrongBuy)
+	}
+	if p.Decay.HalfLifeDays <= 0 || p.Decay.WindowDays < 1 {
+		return invalid("decay.half_life_days must be positive and decay.window_days at least 1")
+	}
+	if c := p.Credibility; c.MinWeight <= 0 || c.MinWeight > 1 || c.MaxWeight < 1 {
+		return invalid("credibility needs 0 < min_weight <= 1 <= max_weight")
+	}
+	if c := p.Credibility; c.CoverageBonus < 0 || c.HitRateImpact < 0 {
+		return invalid("credibility.coverage_bonus and credibility.hit_rate_impact must not be negative")
+	}
+	if c := p.Credibility; c.CoverageHalfCount < 1 || c.MinEvaluatedCalls < 1 || c.HorizonDays < 1 {
+		return invalid("credibility.coverage_half_count, credibility.min_evaluated_calls and credibility.horizon_days must be at least 1")
+	}
+
+	return nil
+}
+
+// ConfidenceOf returns the confidence level of a total score.
+func (p *Profile) ConfidenceOf(score float64) string {
+	if score >= p.Confidence.High {
+		return "High"
+	} else if score >= p.Confidence.Medium {
+		return "Medium"
+	} else {
+		return "Low"
+	}
+}
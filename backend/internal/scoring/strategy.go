@@ -0,0 +1,50 @@
+package scoring
+
+// DefaultStrategy is the strategy used when a request names none. It scores
+// exactly like the engine did before strategies existed.
+const DefaultStrategy = "default"
+
+// Strategy is a named set of factors producing one ranking. Every strategy is
+// scored for every stock, and its scores are stored under its name.
+type Strategy struct {
+	Name        string
+	Description string
+	Factors     *Registry
+}
+
+// DefaultStrategies returns the built-in strategies, the default first.
+func DefaultStrategies() []Strategy {
+	return []Strategy{
+		{
+			Name:        DefaultStrategy,
+			Description: "Balanced view of the latest rating, its change, the price target, the action and coverage depth",
+			Factors:     DefaultRegistry(),
+		},
+		{
+			Name:        "momentum",
+			Description: "Recent upgrades, price target raises and positive actions, regardless of the rating level",
+			Factors: NewRegistry().MustRegister(
+				RatingChangeFactor{},
+				TargetChangeFactor{},
+				ActionFactor{},
+			),
+		},
+		{
+			Name:        "consensus",
+			Description: "Agreement across the latest analyses rather than the newest one alone",
+			Factors: NewRegistry().MustRegister(
+				ConsensusFactor{},
+				CoverageFactor{},
+			),
+		},
+		{
+			Name:        "new-coverage",
+			Description: "Stocks brokerages recently started covering, weighted by their initial rating and target",
+			Factors: NewRegistry().MustRegister(
+				NewCoverageFactor{},
+				RatingFactor{},
+				TargetChangeFactor{},
+			),
+		},
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"stock-api/internal/actions"
+	"stock-api/internal/models"
+)
+
+// ErrInvalidTimelineQuery is returned for timeline filters or cursors that
+// cannot be used.
+var ErrInvalidTimelineQuery = errors.New("invalid analysis timeline query")
+
+const (
+	defaultTimelineLimit = 50
+	maxTimelineLimit     = 100
+)
+
+// GetAnalysisTimeline returns a page of a stock's analyst history, newest
+// first, with the rating and target change of each analysis. It returns nil
+// when the stock does not exist.
+func (s *StockService) GetAnalysisTimeline(symbol string, params models.AnalysisTimelineParams, cursor string) (*models.AnalysisTimeline, error) {
+	if params.ActionType != "" && !actions.Valid(params.ActionType) {
+		return nil, fmt.Errorf("%w: unknown action_type %q", ErrInvalidTimelineQuery, params.ActionType)
+	}
+	if params.From != nil && params.To != nil && !params.From.Before(*params.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidTimelineQuery)
+	}
+	if params.Limit < 1 {
+		params.Limit = defaultTimelineLimit
+	}
+	if params.Limit > maxTimelineLimit {
+		params.Limit = maxTimelineLimit
+	}
+	if cursor != "" {
+		after, err := decodeTimelineCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		params.After = after
+	}
+
+	stock, err := s.repo.GetStockBySymbol(symbol)
+	if err != nil || stock == nil {
+		return nil, err
+	}
+
+	// Read one extra analysis to know whether there is a next page
+	limit := params.Limit
+	params.Limit++
+	analyses, err := s.repo.GetAnalysisTimeline(stock.ID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := &models.AnalysisTimeline{Symbol: stock.Symbol, Analyses: []models.AnalysisTimelineEntry{}}
+	if len(analyses) > limit {
+		analyses = analyses[:limit]
+		last := analyses[limit-1]
+		timeline.HasMore = true
+		timeline.NextCursor = encodeTimelineCursor(models.AnalysisCursor{AnalysisDate: last.AnalysisDate, ID: last.ID})
+	}
+
+	for _, analysis := range analyses {
+		timeline.Analyses = append(timeline.Analyses, models.AnalysisTimelineEntry{
+			StockAnalysis:       analysis,
+			RatingDelta:         ratingDelta(analysis),
+			TargetChangePercent: targetChangePercent(analysis),
+		})
+	}
+
+	return timeline, nil
+}
+
+func ratingDelta(analysis models.StockAnalysis) *int {
+	if analysis.RatingFromCanonical == nil || analysis.RatingToCanonical == nil {
+		return nil
+	}
+	delta := *analysis.RatingToCanonical - *analysis.RatingFromCanonical
+	return &delta
+}
+
+func targetChangePercent(analysis models.StockAnalysis) *float64 {
+	if analysis.TargetFromValue == nil || analysis.TargetToValue == nil || *analysis.TargetFromValue <= 0 {
+		return nil
+	}
+	change := (*analysis.TargetToValue - *analysis.TargetFromValue) / *analysis.TargetFromValue * 100
+	change = math.Round(change*100) / 100
+	return &change
+}
+
+// Cursors are opaque to clients: the analysis date in Unix nanoseconds and
+// the analysis ID, base64 encoded.
+func encodeTimelineCursor(cursor models.AnalysisCursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.AnalysisDate.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTimelineCursor(cursor string) (*models.AnalysisCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidTimelineQuery)
+	}
+
+	date, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidTimelineQuery)
+	}
+	nanos, err := strconv.ParseInt(date, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidTimelineQuery)
+	}
+	analysisID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidTimelineQuery)
+	}
+
+	return &models.AnalysisCursor{AnalysisDate: time.Unix(0, nanos).UTC(), ID: analysisID}, nil
+}
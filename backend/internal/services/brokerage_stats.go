@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"stock-api/internal/models"
+	"stock-api/internal/repository"
+)
+
+// RefreshBrokerageStats recomputes every brokerage's track record, hands it
+// to the engine and rescores every stock, so stored scores use the new
+// credibility weights. Hit rates use the horizon of the active scoring
+// profile. Other replicas pick the new stats up through WatchBrokerageStats.
+func (s *StockService) RefreshBrokerageStats(ctx context.Context) error {
+	lock, ctx, err := s.acquireProcessLock(ctx, repository.ProcessBrokerageStats)
+	if err != nil {
+		return fmt.Errorf("failed to start brokerage stats process: %w", err)
+	}
+	defer lock.Release()
+
+	stored, err := s.brokerageRepo.RefreshBrokerageStats(s.recommendation.Profile().Credibility.HorizonDays)
+	if err != nil {
+		return err
+	}
+
+	if err := s.LoadBrokerageStats(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Refreshed stats of %d brokerages\n", stored)
+
+	stockIDs, err := s.repo.GetAllStockIDs()
+	if err != nil {
+		return fmt.Errorf("failed to list stocks: %w", err)
+	}
+
+	scored, err := s.RescoreStocks(ctx, stockIDs)
+	if err != nil {
+		return fmt.Errorf("failed to rescore stocks with the new credibility weights after scoring %d: %w", scored, err)
+	}
+
+	fmt.Printf("Rescored %d stocks with the new credibility weights\n", scored)
+	return nil
+}
+
+// LoadBrokerageStats hands the stored brokerage track records to the engine.
+// Until it runs every brokerage weighs 1.
+func (s *StockService) LoadBrokerageStats() error {
+	stats, err := s.brokerageRepo.GetBrokerageStats()
+	if err != nil {
+		return fmt.Errorf("failed to load brokerage stats: %w", err)
+	}
+
+	var computedAt time.Time
+	for _, stat := range stats {
+		if stat.ComputedAt.After(computedAt) {
+			computedAt = stat.ComputedAt
+		}
+	}
+
+	s.brokerageStatsMu.Lock()
+	defer s.brokerageStatsMu.Unlock()
+	s.recommendation.SetBrokerageStats(stats)
+	s.brokerageStatsAt = computedAt
+	return nil
+}
+
+// WatchBrokerageStats reloads the brokerage stats every interval if another
+// replica refreshed them since they were loaded, so every replica scores with
+// the same credibility weights. It returns when ctx is cancelled.
+func (s *StockService) WatchBrokerageStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reloadChangedBrokerageStats(); err != nil {
+				fmt.Printf("Warning: failed to reload brokerage stats: %v\n", err)
+			}
+		}
+	}
+}
+
+func (s *StockService) reloadChangedBrokerageStats() error {
+	computedAt, err := s.brokerageRepo.GetBrokerageStatsComputedAt()
+	if err != nil {
+		return err
+	}
+
+	s.brokerageStatsMu.Lock()
+	loaded := s.brokerageStatsAt
+	s.brokerageStatsMu.Unlock()
+	if computedAt.Equal(loaded) {
+		return nil
+	}
+
+	return s.LoadBrokerageStats()
+}
+
+// ListBrokerageStats returns every brokerage's track record with the
+// credibility weight the active scoring profile derives from it.
+func (s *StockService) ListBrokerageStats() ([]models.BrokerageStats, error) {
+	stats, err := s.brokerageRepo.GetBrokerageStats()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := s.recommendation.Profile().Credibility
+	for i := range stats {
+		stats[i].Weight = rules.Weight(stats[i])
+	}
+	return stats, nil
+}
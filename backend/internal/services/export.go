@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+
+	"stock-api/internal/models"
+)
+
+// ExportStocks streams every analysis of the stocks matching filters, joined
+// with their recommendation scores under strategy, to fn in export order.
+// An empty strategy selects the default one.
+func (s *StockService) ExportStocks(ctx context.Context, filters models.StockFilterParams, strategy string, fn func(models.ExportRow) error) error {
+	strategy, err := s.resolveStrategy(strategy)
+	if err != nil {
+		return err
+	}
+	return s.repo.StreamStockExport(ctx, filters, strategy, fn)
+}
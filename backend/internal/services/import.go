@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"stock-api/internal/importer"
+	"stock-api/internal/models"
+)
+
+const (
+	// importBatchSize is how many valid rows are ingested per transaction.
+	importBatchSize = 500
+	// maxImportRowErrors bounds the rejected rows listed in a report.
+	maxImportRowErrors = 1000
+)
+
+// ImportAnalyses loads analyst ratings from a CSV or JSON Lines file. Every
+// row is validated; rejected rows are listed in the report and skipped.
+// Valid rows go through the same batched upsert and rescoring as a sync. A
+// dry run only validates and writes nothing.
+func (s *StockService) ImportAnalyses(ctx context.Context, r io.Reader, opts importer.Options, dryRun bool) (*models.ImportReport, error) {
+	report := &models.ImportReport{
+		Format:    opts.Format,
+		DryRun:    dryRun,
+		RowErrors: []models.ImportRowError{},
+	}
+	if !dryRun {
+		report.Ingest = &models.IngestResult{}
+	}
+
+	touched := make(map[int]struct{})
+	batch := make([]models.AnalysisRecord, 0, importBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 || dryRun {
+			batch = batch[:0]
+			return nil
+		}
+
+		result, err := s.repo.IngestAnalysisBatch(batch, s.retention)
+		if err != nil {
+			return fmt.Errorf("failed to ingest import rows: %w", err)
+		}
+
+		report.Ingest.Add(result)
+		for _, stockID := range result.StockIDs {
+			touched[stockID] = struct{}{}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	err := importer.Read(r, opts, func(row importer.Row) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		report.TotalRows++
+		if len(row.Errors) > 0 {
+			report.InvalidRows++
+			if len(report.RowErrors) < maxImportRowErrors {
+				report.RowErrors = append(report.RowErrors, models.ImportRowError{Line: row.Line, Errors: row.Errors})
+			} else {
+				report.RowErrorsTruncated = true
+			}
+			return nil
+		}
+
+		report.ValidRows++
+		batch = append(batch, row.Record)
+		if len(batch) == importBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	// Rows already committed are scored even when a later batch failed
+	if len(touched) > 0 {
+		scored, rescoreErr := s.rescoreTouched(touched)
+		report.StocksRescored = scored
+		if rescoreErr != nil && err == nil {
+			err = fmt.Errorf("failed to recalculate recommendation scores: %w", rescoreErr)
+		}
+	}
+
+	return report, err
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"stock-api/internal/repository"
+)
+
+// processLock is a lease on a process_control row held by this instance. A
+// background heartbeat keeps the lease alive while the process runs.
+type processLock struct {
+	processRepo *repository.ProcessControlRepository
+	processName string
+	ownerID     string
+	lease       time.Duration
+
+	cancel context.CancelFunc
+	lost   atomic.Bool
+	wg     sync.WaitGroup
+}
+
+// acquireProcessLock takes the lease on processName and returns a context
+// that is cancelled if the lease is lost, e.g. because an admin
+// force-released it or another replica reclaimed it after missed heartbeats.
+func (s *StockService) acquireProcessLock(ctx context.Context, processName string) (*processLock, context.Context, error) {
+	if err := s.processRepo.StartProcess(processName, s.instanceID, s.lockLease); err != nil {
+		return nil, nil, err
+	}
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	lock := &processLock{
+		processRepo: s.processRepo,
+		processName: processName,
+		ownerID:     s.instanceID,
+		lease:       s.lockLease,
+		cancel:      cancel,
+	}
+
+	lock.wg.Add(1)
+	go lock.heartbeat(lockCtx)
+
+	return lock, lockCtx, nil
+}
+
+func (l *processLock) heartbeat(ctx context.Context) {
+	defer l.wg.Done()
+
+	interval := l.lease / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := l.processRepo.HeartbeatProcess(l.processName, l.ownerID, l.lease)
+			if errors.Is(err, repository.ErrLockLost) {
+				fmt.Printf("Warning: lost lock on process %s, stopping it\n", l.processName)
+				l.lost.Store(true)
+				l.cancel()
+				return
+			}
+			if err != nil {
+				fmt.Printf("Warning: failed to heartbeat process %s: %v\n", l.processName, err)
+			}
+		}
+	}
+}
+
+// Lost reports whether the lease was lost while the process was running.
+func (l *processLock) Lost() bool {
+	return l.lost.Load()
+}
+
+// Release stops the heartbeat and frees the lock if this instance still
+// holds it.
+func (l *processLock) Release() {
+	l.cancel()
+	l.wg.Wait()
+
+	if l.Lost() {
+		return
+	}
+
+	if err := l.processRepo.FinishProcess(l.processName, l.ownerID); err != nil {
+		fmt.Printf("Warning: failed to finish process %s: %v\n", l.processName, err)
+	}
+}
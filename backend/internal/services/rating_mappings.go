@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"stock-api/internal/models"
+	"stock-api/internal/ratings"
+)
+
+// ErrInvalidRatingMapping is returned for a mapping that cannot be saved.
+var ErrInvalidRatingMapping = errors.New("invalid rating mapping")
+
+// maxUnmappedRatings bounds the unmapped ratings report.
+const maxUnmappedRatings = 500
+
+// GetRatingScale returns the canonical rating scale.
+func (s *StockService) GetRatingScale() []ratings.ScalePoint {
+	return ratings.Scale
+}
+
+// ListRatingMappings returns every mapping, or only those of brokerage when
+// it is not empty.
+func (s *StockService) ListRatingMappings(brokerage string) ([]models.RatingMapping, error) {
+	return s.ratingRepo.List(brokerage)
+}
+
+// GetUnmappedRatings reports the raw ratings of stored analyses that no
+// mapping resolves, so they can be added to the taxonomy.
+func (s *StockService) GetUnmappedRatings() ([]models.UnmappedRating, error) {
+	return s.ratingRepo.GetUnmapped(maxUnmappedRatings)
+}
+
+// SaveRatingMapping creates or changes a mapping, then re-resolves the stored
+// analyses and rescores the stocks whose canonical ratings changed.
+func (s *StockService) SaveRatingMapping(ctx context.Context, input models.RatingMappingInput) (*models.RatingMappingChange, error) {
+	if ratings.Normalize(input.RawRating) == "" {
+		return nil, fmt.Errorf("%w: raw_rating is required", ErrInvalidRatingMapping)
+	}
+	if len(ratings.Normalize(input.RawRating)) > 50 {
+		return nil, fmt.Errorf("%w: raw_rating must be at most 50 characters", ErrInvalidRatingMapping)
+	}
+	if len(strings.TrimSpace(input.Brokerage)) > 100 {
+		return nil, fmt.Errorf("%w: brokerage must be at most 100 characters", ErrInvalidRatingMapping)
+	}
+	if !ratings.Valid(input.CanonicalValue) {
+		return nil, fmt.Errorf("%w: canonical_value must be between %d and %d", ErrInvalidRatingMapping, ratings.StrongSell, ratings.StrongBuy)
+	}
+
+	mapping, err := s.ratingRepo.Upsert(input.Brokerage, input.RawRating, input.CanonicalValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.applyRatingMappings(ctx, mapping)
+}
+
+// DeleteRatingMapping removes a mapping and re-resolves the stored analyses
+// like SaveRatingMapping. It returns nil if the mapping does not exist.
+func (s *StockService) DeleteRatingMapping(ctx context.Context, id int) (*models.RatingMappingChange, error) {
+	mapping, err := s.ratingRepo.Delete(id)
+	if err != nil || mapping == nil {
+		return nil, err
+	}
+
+	return s.applyRatingMappings(ctx, mapping)
+}
+
+func (s *StockService) applyRatingMappings(ctx context.Context, mapping *models.RatingMapping) (*models.RatingMappingChange, error) {
+	change := &models.RatingMappingChange{Mapping: mapping}
+
+	updated, stockIDs, err := s.ratingRepo.ResolveStoredRatings(false)
+	change.AnalysesUpdated = updated
+	if err != nil {
+		return change, fmt.Errorf("failed to apply rating mappings: %w", err)
+	}
+
+	archived, err := s.ratingRepo.ResolveArchivedRatings()
+	change.AnalysesUpdated += archived
+	if err != nil {
+		return change, fmt.Errorf("failed to apply rating mappings to archived analyses: %w", err)
+	}
+
+	if len(stockIDs) > 0 {
+		rescored, err := s.RescoreStocks(ctx, stockIDs)
+		change.StocksRescored = rescored
+		if err != nil {
+			return change, fmt.Errorf("failed to rescore stocks: %w", err)
+		}
+	}
+
+	return change, nil
+}
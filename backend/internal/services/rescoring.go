@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"stock-api/internal/models"
+	"stock-api/internal/repository"
+)
+
+const (
+	// scoringAnalysisLimit caps how many of the newest analyses in the decay
+	// window feed a score.
+	scoringAnalysisLimit = 50
+	// rescoreBatchSize is how many stocks a worker loads and stores at once.
+	rescoreBatchSize = 200
+)
+
+// RescoreStocks recomputes and stores the recommendation score of every
+// given stock exactly once. Stocks are split into batches that a pool of
+// workers loads, scores and upserts with one query each. It returns the
+// number of stocks scored.
+func (s *StockService) RescoreStocks(ctx context.Context, stockIDs []int) (int, error) {
+	batches := make(chan []int)
+	var (
+		mu       sync.Mutex
+		scored   int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	workers := s.scoringWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				count, err := s.rescoreBatch(batch)
+
+				mu.Lock()
+				scored += count
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for start := 0; start < len(stockIDs); start += rescoreBatchSize {
+		end := start + rescoreBatchSize
+		if end > len(stockIDs) {
+			end = len(stockIDs)
+		}
+
+		select {
+		case batches <- stockIDs[start:end]:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+
+	return scored, firstErr
+}
+
+// rescoreTouched rescores the stocks a sync or import wrote. It ignores the
+// caller's context so committed data is never left with stale scores.
+func (s *StockService) rescoreTouched(touched map[int]struct{}) (int, error) {
+	stockIDs := make([]int, 0, len(touched))
+	for id := range touched {
+		stockIDs = append(stockIDs, id)
+	}
+
+	return s.RescoreStocks(context.Background(), stockIDs)
+}
+
+func (s *StockService) rescoreBatch(stockIDs []int) (int, error) {
+	since := s.recommendation.Profile().Decay.Since(time.Now())
+	stocks, err := s.repo.GetStocksWithLatestAnalysis(stockIDs, since, scoringAnalysisLimit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load stocks for scoring: %w", err)
+	}
+
+	// Every strategy is scored for every stock
+	scores := make([]models.RecommendationScore, 0, len(stocks)*len(s.recommendation.Strategies()))
+	for _, stock := range stocks {
+		scores = append(scores, s.recommendation.ScoreAll(stock)...)
+	}
+
+	if err := s.recScoreRepo.UpsertRecommendationScores(scores); err != nil {
+		return 0, err
+	}
+
+	return len(stocks), nil
+}
+
+// RescoreAllStocks recomputes the score of every stock. It runs under its
+// own process lock so only one replica rescores at a time.
+func (s *StockService) RescoreAllStocks(ctx context.Context) error {
+	lock, ctx, err := s.acquireProcessLock(ctx, repository.ProcessRecommendationRescore)
+	if err != nil {
+		return fmt.Errorf("failed to start recommendation rescore process: %w", err)
+	}
+	defer lock.Release()
+
+	// Another replica may have refreshed the credibility weights since the
+	// last watch tick
+	if err := s.reloadChangedBrokerageStats(); err != nil {
+		fmt.Printf("Warning: failed to reload brokerage stats: %v\n", err)
+	}
+
+	stockIDs, err := s.repo.GetAllStockIDs()
+	if err != nil {
+		return fmt.Errorf("failed to list stocks: %w", err)
+	}
+
+	scored, err := s.RescoreStocks(ctx, stockIDs)
+	if err != nil {
+		return fmt.Errorf("failed to rescore stocks after scoring %d: %w", scored, err)
+	}
+
+	fmt.Printf("Successfully rescored %d stocks\n", scored)
+	return nil
+}
+
+// IsRescoreRunning reports whether a full rescore holds a live lease.
+func (s *StockService) IsRescoreRunning() (bool, error) {
+	process, err := s.processRepo.GetProcessControl(repository.ProcessRecommendationRescore)
+	if err != nil || process == nil {
+		return false, err
+	}
+
+	return process.IsRunning && !process.LockStale, nil
+}
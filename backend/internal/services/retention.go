@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"stock-api/internal/repository"
+)
+
+const (
+	// retentionBatchSize is how many stocks are archived per transaction.
+	retentionBatchSize = 500
+	// maxArchivedAnalyses bounds the archived history returned for a stock.
+	maxArchivedAnalyses = 500
+)
+
+// ApplyRetention archives the analyses of every stock that the retention
+// policy no longer keeps. Ingestion already does this for the stocks it
+// touches; this catches analyses that aged out of stocks no longer updated
+// and applies a tightened policy to the whole table. Stocks that lost
+// analyses are rescored. It does nothing when every analysis is kept, so the
+// scheduler does not register it then.
+func (s *StockService) ApplyRetention(ctx context.Context) error {
+	if s.retention.Mode == repository.RetentionKeepAll {
+		return nil
+	}
+
+	lock, ctx, err := s.acquireProcessLock(ctx, repository.ProcessAnalysisRetention)
+	if err != nil {
+		return fmt.Errorf("failed to start analysis retention process: %w", err)
+	}
+	defer lock.Release()
+
+	stockIDs, err := s.repo.GetAllStockIDs()
+	if err != nil {
+		return fmt.Errorf("failed to list stocks: %w", err)
+	}
+
+	archived := 0
+	touched := make(map[int]struct{})
+	for start := 0; start < len(stockIDs); start += retentionBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start + retentionBatchSize
+		if end > len(stockIDs) {
+			end = len(stockIDs)
+		}
+
+		count, archivedFrom, err := s.repo.ArchiveAnalyses(stockIDs[start:end], s.retention)
+		if err != nil {
+			return fmt.Errorf("failed to archive analyses after archiving %d: %w", archived, err)
+		}
+		archived += count
+		for _, id := range archivedFrom {
+			touched[id] = struct{}{}
+		}
+	}
+
+	if _, err := s.rescoreTouched(touched); err != nil {
+		return err
+	}
+
+	fmt.Printf("Archived %d analyses (%s)\n", archived, s.retention)
+	return nil
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"fmt"
+
+	"stock-api/internal/scoring"
+)
+
+// GetScoringProfile returns the active scoring profile.
+func (s *StockService) GetScoringProfile() *scoring.Profile {
+	return s.recommendation.Profile()
+}
+
+// ReloadScoringProfile rereads the configured profile file and makes it the
+// active profile. An invalid file leaves the active profile in place. Stored
+// scores keep the version they were computed with until they are rescored.
+func (s *StockService) ReloadScoringProfile() (*scoring.Profile, error) {
+	if s.profilePath == "" {
+		return nil, fmt.Errorf("%w: no profile file is configured (SCORING_PROFILE)", scoring.ErrInvalidProfile)
+	}
+
+	profile, err := scoring.LoadProfile(s.profilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recommendation.SetProfile(profile)
+	fmt.Printf("Loaded scoring profile %s from %s\n", profile.Version, s.profilePath)
+	return profile, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"stock-api/internal/clients"
+	"stock-api/internal/models"
+	"stock-api/internal/repository"
+)
+
+// SyncAllStocks walks every page from the analyst data provider. When resume
+// is true and the previous run did not complete, it continues from the stored
+// checkpoint instead of the first page. Every invocation is recorded in
+// sync_runs. Pages are fetched ahead of a pool of writers that ingest each
+// page in a single transaction; the checkpoint only advances past pages whose
+// predecessors are all stored. Cancelling ctx, or calling CancelSync, stops
+// the run and leaves the checkpoint in place for the next run.
+func (s *StockService) SyncAllStocks(ctx context.Context, resume bool) (err error) {
+	// Start the process
+	lock, ctx, err := s.acquireProcessLock(ctx, repository.ProcessStockSync)
+	if err != nil {
+		return fmt.Errorf("failed to start stock sync process: %w", err)
+	}
+
+	// Ensure process is marked as finished even if there's an error
+	defer lock.Release()
+
+	// Holding the lock means any run still marked running was orphaned by a
+	// crashed holder whose lease expired
+	if err := s.syncRunRepo.AbandonRunningSyncRuns(repository.ProcessStockSync, "abandoned: process lock lease expired"); err != nil {
+		fmt.Printf("Warning: failed to mark orphaned stock sync runs as abandoned: %v\n", err)
+	}
+
+	if runScoped, ok := s.provider.(clients.RunScoped); ok {
+		runScoped.BeginRun()
+	}
+
+	run := &models.SyncRun{ProcessName: repository.ProcessStockSync}
+	nextPage := ""
+	totalProcessed := 0
+
+	if resume {
+		checkpoint, err := s.processRepo.GetStockSyncCheckpoint()
+		if err != nil {
+			return fmt.Errorf("failed to read stock sync checkpoint: %w", err)
+		}
+		if checkpoint != "" {
+			fmt.Printf("Resuming stock sync from checkpoint %s\n", checkpoint)
+			nextPage = checkpoint
+			run.Resumed = true
+			run.ResumedFrom = checkpoint
+		}
+	} else if err := s.processRepo.ClearStockSyncCheckpoint(lock.ownerID); err != nil {
+		return fmt.Errorf("failed to reset stock sync checkpoint: %w", err)
+	}
+
+	if err := s.syncRunRepo.CreateSyncRun(run); err != nil {
+		return fmt.Errorf("failed to record stock sync run: %w", err)
+	}
+
+	defer func() {
+		run.Status = models.SyncRunStatusCompleted
+		if lock.Lost() {
+			run.Status = models.SyncRunStatusFailed
+			run.ErrorMessage = fmt.Sprintf("%v: %v", repository.ErrLockLost, err)
+		} else if errors.Is(err, context.Canceled) {
+			run.Status = models.SyncRunStatusCancelled
+			run.ErrorMessage = err.Error()
+		} else if err != nil {
+			run.Status = models.SyncRunStatusFailed
+			run.ErrorMessage = err.Error()
+		}
+		if finishErr := s.syncRunRepo.FinishSyncRun(run); finishErr != nil {
+			fmt.Printf("Warning: failed to record end of stock sync run %d: %v\n", run.ID, finishErr)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	progress := &models.SyncProgress{
+		Phase:     models.SyncPhaseFetching,
+		RunID:     run.ID,
+		Resumed:   run.Resumed,
+		StartedAt: &run.StartedAt,
+	}
+	if lastRun, err := s.syncRunRepo.GetLastFullSyncRun(repository.ProcessStockSync); err == nil && lastRun != nil && !run.Resumed {
+		progress.EstimatedTotalPages = lastRun.PagesFetched
+	}
+
+	s.syncTracker.begin(cancel, progress)
+	defer s.syncTracker.end()
+
+	// Scores are recomputed once per distinct stock at the end of the run,
+	// including runs that fail or are cancelled after committing some pages
+	touched := make(map[int]struct{})
+	defer func() {
+		if len(touched) == 0 {
+			return
+		}
+
+		s.syncTracker.update(func(p *models.SyncProgress) { p.Phase = models.SyncPhaseRescoring })
+
+		scored, rescoreErr := s.rescoreTouched(touched)
+		if rescoreErr != nil {
+			fmt.Printf("Warning: failed to recalculate recommendation scores: %v\n", rescoreErr)
+		}
+		fmt.Printf("Recalculated recommendation scores for %d stocks\n", scored)
+	}()
+
+	pipeline := &syncPipeline{
+		provider: s.provider,
+		ingest: func(items []clients.StockAnalysis) (*models.IngestResult, error) {
+			return s.repo.IngestAnalysisBatch(toAnalysisRecords(items), s.retention)
+		},
+		writers:    s.syncWriters,
+		pageBuffer: s.syncPageBuffer,
+	}
+
+	// Pages arrive here in order, each already committed by a writer
+	err = pipeline.run(ctx, nextPage, func(page ingestedPage) {
+		result := page.result
+
+		run.PagesFetched++
+		run.StocksCreated += result.StocksCreated
+		run.StocksUpdated += result.StocksUpdated
+		run.AnalysesInserted += result.AnalysesInserted
+		run.AnalysesUpdated += result.AnalysesUpdated
+		run.AnalysesUnchanged += result.AnalysesUnchanged
+		run.AnalysesFailed += result.AnalysesFailed
+		totalProcessed += len(page.items) - result.AnalysesFailed
+
+		for _, stockID := range result.StockIDs {
+			touched[stockID] = struct{}{}
+		}
+
+		s.syncTracker.update(func(p *models.SyncProgress) {
+			p.PagesProcessed = run.PagesFetched
+			p.ItemsProcessed += len(page.items)
+			p.Errors += result.AnalysesFailed
+		})
+
+		fmt.Printf("Processed %d stocks in page %s (total: %d)\n", len(page.items), page.token, totalProcessed)
+
+		if err := s.syncRunRepo.UpdateSyncRunProgress(run); err != nil {
+			fmt.Printf("Warning: failed to record progress of stock sync run %d: %v\n", run.ID, err)
+		}
+
+		if page.next == "" {
+			return
+		}
+
+		if err := s.processRepo.SaveStockSyncCheckpoint(lock.ownerID, page.next); err != nil {
+			fmt.Printf("Warning: failed to save stock sync checkpoint %s: %v\n", page.next, err)
+		}
+	})
+	if err != nil {
+		// The checkpoint still points at the first page not stored, so the
+		// next resumed run picks up from there
+		if errors.Is(err, context.Canceled) {
+			return fmt.Errorf("stock sync cancelled after processing %d stocks: %w", totalProcessed, err)
+		}
+		return fmt.Errorf("stock sync failed after processing %d stocks: %w", totalProcessed, err)
+	}
+
+	if err := s.processRepo.ClearStockSyncCheckpoint(lock.ownerID); err != nil {
+		fmt.Printf("Warning: failed to clear stock sync checkpoint: %v\n", err)
+	}
+
+	fmt.Printf("Successfully processed %d stocks total\n", totalProcessed)
+	return nil
+}
+
+// GetCurrentSync returns live progress of the sync running in this process.
+func (s *StockService) GetCurrentSync() *models.SyncProgress {
+	return s.syncTracker.snapshot()
+}
+
+// CancelSync stops the sync running in this process and reports whether one
+// was running. The run finishes in the background with status cancelled.
+func (s *StockService) CancelSync() bool {
+	return s.syncTracker.requestCancel()
+}
+
+func toAnalysisRecords(items []clients.StockAnalysis) []models.AnalysisRecord {
+	records := make([]models.AnalysisRecord, 0, len(items))
+	for _, item := range items {
+		records = append(records, models.AnalysisRecord{
+			Symbol:       item.Ticker,
+			Company:      item.Company,
+			TargetFrom:   item.TargetFrom,
+			TargetTo:     item.TargetTo,
+			Action:       item.Action,
+			Brokerage:    item.Brokerage,
+			RatingFrom:   item.RatingFrom,
+			RatingTo:     item.RatingTo,
+			AnalysisDate: item.Time,
+		})
+	}
+	return records
+}
+
+func (s *StockService) GetSyncRunsPaginated(page, pageSize int, status string) (*models.PaginatedResponse[models.SyncRun], error) {
+	return s.syncRunRepo.GetSyncRunsPaginated(page, pageSize, status)
+}
+
+func (s *StockService) GetSyncRun(id int) (*models.SyncRun, error) {
+	return s.syncRunRepo.GetSyncRunByID(id)
+}
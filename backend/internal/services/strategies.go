@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"stock-api/internal/models"
+	"stock-api/internal/scoring"
+)
+
+// ErrUnknownStrategy is returned when a request names a strategy the engine
+// does not have.
+var ErrUnknownStrategy = errors.New("unknown scoring strategy")
+
+// ListStrategies describes the scoring strategies recommendations can be
+// ranked by.
+func (s *StockService) ListStrategies() []models.ScoringStrategy {
+	strategies := []models.ScoringStrategy{}
+	for _, strategy := range s.recommendation.Strategies() {
+		factors := []string{}
+		for _, factor := range strategy.Factors.Factors() {
+			factors = append(factors, factor.Name())
+		}
+		strategies = append(strategies, models.ScoringStrategy{
+			Name:        strategy.Name,
+			Description: strategy.Description,
+			Factors:     factors,
+			Default:     strategy.Name == scoring.DefaultStrategy,
+		})
+	}
+	return strategies
+}
+
+// resolveStrategy returns the strategy a request asked for, or the default
+// one when it asked for none.
+func (s *StockService) resolveStrategy(name string) (string, error) {
+	if name == "" {
+		return scoring.DefaultStrategy, nil
+	}
+	if _, ok := s.recommendation.Strategy(name); !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownStrategy, name)
+	}
+	return name, nil
+}
+
+// ScoreUnscoredStrategies rescores every stock when a strategy has no stored
+// scores yet, as after the upgrade that introduced strategies, where only the
+// default strategy kept its rows. Until then such a strategy ranks nothing.
+func (s *StockService) ScoreUnscoredStrategies(ctx context.Context) error {
+	counts, err := s.recScoreRepo.CountScoresByStrategy()
+	if err != nil {
+		return fmt.Errorf("failed to count scores by strategy: %w", err)
+	}
+
+	missing := []string{}
+	for _, strategy := range s.recommendation.Strategies() {
+		if counts[strategy.Name] == 0 {
+			missing = append(missing, strategy.Name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	stockIDs, err := s.repo.GetAllStockIDs()
+	if err != nil {
+		return fmt.Errorf("failed to list stocks: %w", err)
+	}
+	if len(stockIDs) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Scoring strategies without stored scores: %s\n", strings.Join(missing, ", "))
+	return s.RescoreAllStocks(ctx)
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"stock-api/internal/clients"
+	"stock-api/internal/models"
+)
+
+// fetchedPage is one page read from the analyst data provider. seq is its
+// position in the run, token the page token it was requested with and next
+// the token of the page after it.
+type fetchedPage struct {
+	seq   int
+	token string
+	next  string
+	items []clients.StockAnalysis
+}
+
+// ingestedPage is a fetched page after a writer stored it.
+type ingestedPage struct {
+	fetchedPage
+	result *models.IngestResult
+	err    error
+}
+
+// syncPipeline fetches pages on one goroutine and stores them with a pool of
+// writers. Pagination is sequential, but the fetcher runs ahead of the
+// writers by up to pageBuffer pages and blocks once the buffer is full, so a
+// slow database throttles the provider instead of piling pages up in memory.
+type syncPipeline struct {
+	provider   clients.AnalystDataProvider
+	ingest     func(items []clients.StockAnalysis) (*models.IngestResult, error)
+	writers    int
+	pageBuffer int
+}
+
+// run syncs every page from startPage onward. Pages can finish out of order,
+// but commit is called strictly in page order, so the caller can move the
+// checkpoint past a page once everything before it is stored. The first
+// fetch or write error stops the pipeline; pages stored after the failed one
+// are simply written again by the next resumed run.
+func (p *syncPipeline) run(ctx context.Context, startPage string, commit func(page ingestedPage)) error {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	writers := p.writers
+	if writers < 1 {
+		writers = 1
+	}
+	pageBuffer := p.pageBuffer
+	if pageBuffer < 1 {
+		pageBuffer = 1
+	}
+
+	pages := make(chan fetchedPage, pageBuffer)
+	results := make(chan ingestedPage, writers)
+
+	fetchErr := make(chan error, 1)
+	go func() {
+		defer close(pages)
+		fetchErr <- p.fetch(ctx, startPage, pages)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pages {
+				// Drain without writing once the run is stopping
+				if ctx.Err() != nil {
+					continue
+				}
+				result, err := p.ingest(page.items)
+				results <- ingestedPage{fetchedPage: page, result: result, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var writeErr error
+	pending := make(map[int]ingestedPage)
+	nextSeq := 0
+	for page := range results {
+		if page.err != nil {
+			if writeErr == nil {
+				writeErr = fmt.Errorf("failed to ingest page %q: %w", page.token, page.err)
+			}
+			stop()
+			continue
+		}
+		if writeErr != nil {
+			continue
+		}
+
+		pending[page.seq] = page
+		for {
+			next, ok := pending[nextSeq]
+			if !ok {
+				break
+			}
+			delete(pending, nextSeq)
+			nextSeq++
+			commit(next)
+		}
+	}
+
+	if writeErr != nil {
+		return writeErr
+	}
+	return <-fetchErr
+}
+
+// fetch walks the provider's pagination and hands each page to the writers.
+func (p *syncPipeline) fetch(ctx context.Context, nextPage string, pages chan<- fetchedPage) error {
+	for seq := 0; ; seq++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		response, err := p.provider.GetStocksList(ctx, nextPage)
+		if err != nil {
+			fmt.Println("🔴🔴 ~ Error fetching stocks from analyst data provider:", err)
+			if errors.Is(err, clients.ErrUnauthorized) {
+				return fmt.Errorf("analyst data provider rejected credentials, check KAREN_AI_TOKEN: %w", err)
+			}
+			return fmt.Errorf("failed to fetch page %q from API: %w", nextPage, err)
+		}
+
+		page := fetchedPage{seq: seq, token: nextPage, next: response.NextPage, items: response.Items}
+		select {
+		case pages <- page:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if response.NextPage == "" {
+			return nil
+		}
+		nextPage = response.NextPage
+	}
+}
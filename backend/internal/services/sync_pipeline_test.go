@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stock-api/internal/clients"
+	"stock-api/internal/fakekarenai"
+	"stock-api/internal/models"
+)
+
+// newTestPipeline runs the fixture set through a fake KarenAI server. Ingest
+// takes longer for every other page, so writers finish pages out of order.
+func newTestPipeline(t *testing.T, opts fakekarenai.Options) (*syncPipeline, *fakekarenai.Server, int) {
+	t.Helper()
+
+	items, err := fakekarenai.LoadFixtures("../../fixtures/karenai")
+	if err != nil {
+		t.Fatalf("load fixtures: %v", err)
+	}
+
+	fake := fakekarenai.NewServer(items, opts)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	var calls int32
+	pipeline := &syncPipeline{
+		provider: clients.NewKarenAIClient("", server.URL),
+		ingest: func(items []clients.StockAnalysis) (*models.IngestResult, error) {
+			if atomic.AddInt32(&calls, 1)%2 == 1 {
+				time.Sleep(20 * time.Millisecond)
+			}
+			return &models.IngestResult{AnalysesInserted: len(items)}, nil
+		},
+		writers:    4,
+		pageBuffer: 4,
+	}
+	return pipeline, fake, len(items)
+}
+
+func TestSyncPipelineCommitsPagesInOrderDespiteFaults(t *testing.T) {
+	pipeline, fake, itemCount := newTestPipeline(t, fakekarenai.Options{
+		PageSize:         5,
+		RateLimitEvery:   5,
+		ServerErrorEvery: 7,
+	})
+
+	var committed []ingestedPage
+	err := pipeline.run(context.Background(), "", func(page ingestedPage) {
+		committed = append(committed, page)
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if len(committed) != fake.PageCount() {
+		t.Fatalf("committed %d pages, want %d", len(committed), fake.PageCount())
+	}
+
+	inserted := 0
+	token := ""
+	for i, page := range committed {
+		if page.seq != i {
+			t.Fatalf("commit %d is page %d", i, page.seq)
+		}
+		if page.token != token {
+			t.Fatalf("page %d was requested with %q, want %q", i, page.token, token)
+		}
+		token = page.next
+		inserted += page.result.AnalysesInserted
+	}
+	if token != "" {
+		t.Fatalf("last page points to %q, want end of data", token)
+	}
+	if inserted != itemCount {
+		t.Fatalf("ingested %d items, want %d", inserted, itemCount)
+	}
+
+	if stats := fake.Stats(); stats.RateLimited == 0 || stats.ServerErrors == 0 {
+		t.Fatalf("expected injected faults, got %+v", stats)
+	}
+}
+
+func TestSyncPipelineStopsAtMalformedPage(t *testing.T) {
+	pipeline, _, _ := newTestPipeline(t, fakekarenai.Options{
+		PageSize:       5,
+		MalformedEvery: 4,
+	})
+
+	var committed []ingestedPage
+	err := pipeline.run(context.Background(), "", func(page ingestedPage) {
+		committed = append(committed, page)
+	})
+	if !errors.Is(err, clients.ErrDecode) {
+		t.Fatalf("got error %v, want %v", err, clients.ErrDecode)
+	}
+
+	// Only the pages before the malformed one may move the checkpoint
+	if len(committed) != 3 {
+		t.Fatalf("committed %d pages, want 3", len(committed))
+	}
+	for i, page := range committed {
+		if page.seq != i {
+			t.Fatalf("commit %d is page %d", i, page.seq)
+		}
+	}
+}
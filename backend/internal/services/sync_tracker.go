@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"stock-api/internal/models"
+)
+
+// syncTracker holds the progress and cancel function of the sync running in
+// this process so it can be observed and stopped from HTTP handlers.
+type syncTracker struct {
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	progress *models.SyncProgress
+}
+
+func (t *syncTracker) begin(cancel context.CancelFunc, progress *models.SyncProgress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	progress.Running = true
+	t.cancel = cancel
+	t.progress = progress
+}
+
+func (t *syncTracker) update(fn func(progress *models.SyncProgress)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.progress != nil {
+		fn(t.progress)
+	}
+}
+
+func (t *syncTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cancel = nil
+	t.progress = nil
+}
+
+// requestCancel cancels the running sync and reports whether there was one.
+func (t *syncTracker) requestCancel() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cancel == nil {
+		return false
+	}
+
+	t.progress.CancelRequested = true
+	t.cancel()
+	return true
+}
+
+// snapshot returns a copy of the current progress with the ETA filled in from
+// the average page duration so far.
+func (t *syncTracker) snapshot() *models.SyncProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.progress == nil {
+		return &models.SyncProgress{Running: false}
+	}
+
+	progress := *t.progress
+	remainingPages := progress.EstimatedTotalPages - progress.PagesProcessed
+	if progress.StartedAt != nil && progress.PagesProcessed > 0 && remainingPages > 0 {
+		perPage := time.Since(*progress.StartedAt) / time.Duration(progress.PagesProcessed)
+		remaining := perPage * time.Duration(remainingPages)
+		seconds := int(remaining.Seconds())
+		eta := time.Now().Add(remaining)
+		progress.ETASeconds = &seconds
+		progress.ETA = &eta
+	}
+
+	return &progress
+}
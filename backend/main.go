@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"stock-api/internal/api"
+	"stock-api/internal/clients"
 	"stock-api/internal/config"
 	"stock-api/internal/database"
 	"stock-api/internal/middleware"
+	"stock-api/internal/repository"
+	"stock-api/internal/scheduler"
+	"stock-api/internal/scoring"
 	"stock-api/internal/services"
 
 	"github.com/gorilla/mux"
@@ -19,6 +26,9 @@ import (
 
 func main() {
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
 
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
@@ -26,14 +36,80 @@ func main() {
 	}
 	defer db.Close()
 
+	// migrate manages the schema itself, so it runs before the startup migration
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], db); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := database.Migrate(db); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
-	stockService := services.NewStockService(db, cfg.KarenAIToken)
+	provider, err := clients.NewAnalystDataProvider(cfg.DataProvider, clients.ProviderOptions{
+		APIToken:  cfg.KarenAIToken,
+		BaseURL:   cfg.KarenAIBaseURL,
+		RecordDir: cfg.RecordDir,
+		ReplayDir: cfg.ReplayDir,
+	})
+	if err != nil {
+		log.Fatal("Failed to create analyst data provider:", err)
+	}
+
+	retention, err := repository.NewRetentionPolicy(cfg.AnalysisRetention, cfg.AnalysisKeepCount, cfg.AnalysisKeepAge)
+	if err != nil {
+		log.Fatal("Invalid analysis retention policy:", err)
+	}
+
+	profile, err := scoring.LoadProfile(cfg.ScoringProfile)
+	if err != nil {
+		log.Fatal("Failed to load scoring profile:", err)
+	}
+
+	stockService := services.NewStockService(db, provider, cfg, retention, profile)
+	if err := stockService.LoadBrokerageStats(); err != nil {
+		log.Printf("Scoring without brokerage credibility weights: %v", err)
+	}
+
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:], stockService); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	sched := scheduler.New(repository.NewProcessControlRepository(db), cfg.SchedulerEnabled, cfg.SchedulerTick, cfg.InstanceID)
+	sched.Register(repository.ProcessStockSync, func(ctx context.Context) error {
+		return stockService.SyncAllStocks(ctx, true)
+	})
+	sched.Register(repository.ProcessRecommendationRescore, stockService.RescoreAllStocks)
+	// Keeping every analysis leaves the retention job nothing to do, and as it
+	// would never record an execution it would be due on every tick
+	if retention.Mode != repository.RetentionKeepAll {
+		sched.Register(repository.ProcessAnalysisRetention, stockService.ApplyRetention)
+	}
+	sched.Register(repository.ProcessBrokerageStats, stockService.RefreshBrokerageStats)
+	// Scheduled jobs and the syncs and rescores requests start stop with the
+	// server, so a shutdown lets a running sync finish its page and release
+	// its lease
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	sched.Start(ctx)
+	background := api.NewBackground(ctx)
+	background.Go(func(ctx context.Context) {
+		if err := stockService.ScoreUnscoredStrategies(ctx); err != nil {
+			log.Printf("Failed to score strategies without stored scores: %v", err)
+		}
+	})
+	background.Go(func(ctx context.Context) {
+		stockService.WatchBrokerageStats(ctx, cfg.SchedulerTick)
+	})
+
 	router := mux.NewRouter()
 
-	api.SetupRoutes(router, stockService)
+	api.SetupRoutes(router, stockService, sched, background)
 
 	// Rate limiting: 100 requests per minute per IP
 	rateLimiter := middleware.NewIPRateLimiter(rate.Every(60*time.Second/100), 10)
@@ -51,6 +127,28 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, handler))
+	server := &http.Server{Addr: ":" + port, Handler: handler}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		stop()
+		sched.Wait()
+		background.Wait()
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown failed: %v", err)
+	}
+	sched.Wait()
+	background.Wait()
 }